@@ -0,0 +1,110 @@
+// Package audit собирает записи журнала аудита: кто, что и над чем сделал.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+
+	"todo-api/middleware"
+	"todo-api/models"
+)
+
+// Действия
+const (
+	Register   = "user.register"
+	Login      = "user.login"
+	UserUpdate = "user.update"
+	UserDelete = "user.delete"
+	// PasswordChange — смена пароля; сам хеш в журнал не попадает
+	PasswordChange = "user.password_change"
+	TodoCreate     = "todo.create"
+	TodoUpdate     = "todo.update"
+	TodoDelete     = "todo.delete"
+	// TodoRestore и TodoPurge — возврат из корзины и окончательное удаление
+	TodoRestore = "todo.restore"
+	TodoPurge   = "todo.purge"
+	// TodoUndo — отмена недавней операции по undo_token
+	TodoUndo = "todo.undo"
+)
+
+// Actions — все действия, по которым можно фильтровать журнал
+var Actions = []string{Register, Login, UserUpdate, PasswordChange, UserDelete, TodoCreate, TodoUpdate, TodoDelete, TodoRestore, TodoPurge, TodoUndo}
+
+// Исходы
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+// Типы объектов
+const (
+	TargetUser = "user"
+	TargetTodo = "todo"
+)
+
+// Change — изменение одного поля
+type Change struct {
+	From any `json:"from,omitempty"`
+	To   any `json:"to,omitempty"`
+}
+
+// Entry собирает запись об успешном действии actorID над объектом targetType/targetID.
+// IP и ID запроса берутся из ctx. actorID == 0 — действующий не известен.
+func Entry(ctx context.Context, actorID int, action, targetType string, targetID int) models.AuditEntry {
+	entry := models.AuditEntry{
+		Action:     action,
+		Outcome:    Success,
+		TargetType: targetType,
+		IP:         middleware.GetClientIP(ctx),
+		RequestID:  middleware.GetRequestID(ctx),
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+	if targetID != 0 {
+		entry.TargetID = &targetID
+	}
+	return entry
+}
+
+// Diff возвращает изменённые поля между before и after по их JSON-представлению,
+// так что поля с json:"-" (хэш пароля) в журнал не попадают. nil вместо
+// before — объект создан, вместо after — удалён.
+func Diff(before, after any) (json.RawMessage, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	keys := slices.Sorted(maps.Keys(from))
+	keys = append(keys, slices.Sorted(maps.Keys(to))...)
+	for _, key := range keys {
+		if _, done := changes[key]; done {
+			continue
+		}
+		if !reflect.DeepEqual(from[key], to[key]) {
+			changes[key] = Change{From: from[key], To: to[key]}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+func fields(v any) (map[string]any, error) {
+	m := map[string]any{}
+	if v == nil {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"todo-api/models"
+)
+
+func TestDiff(t *testing.T) {
+	photo := "http://localhost/uploads/abc"
+	todo := models.Todo{ID: 1, Title: "Купить молоко", UserID: 7}
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          string
+	}{
+		{"no changes", todo, todo, `{}`},
+		{
+			"changed fields only",
+			todo,
+			models.Todo{ID: 1, Title: "Купить хлеб", Done: true, UserID: 7},
+			`{"done":{"from":false,"to":true},"title":{"from":"Купить молоко","to":"Купить хлеб"}}`,
+		},
+		{
+			"added optional field",
+			todo,
+			models.Todo{ID: 1, Title: "Купить молоко", UserID: 7, PhotoURL: &photo},
+			`{"photo_url":{"to":"http://localhost/uploads/abc"}}`,
+		},
+		{
+			"created",
+			nil,
+			models.Todo{ID: 2, Title: "Новая", UserID: 7},
+			`{"done":{"to":false},"id":{"to":2},"title":{"to":"Новая"},"user_id":{"to":7}}`,
+		},
+		{
+			"deleted",
+			models.Todo{ID: 2, Title: "Старая", UserID: 7},
+			nil,
+			`{"done":{"from":false},"id":{"from":2},"title":{"from":"Старая"},"user_id":{"from":7}}`,
+		},
+		{
+			"json:\"-\" fields never logged",
+			models.User{ID: 1, Username: "alice", PasswordHash: "old", FailedLoginAttempts: 1},
+			models.User{ID: 1, Username: "alice", PasswordHash: "new", FailedLoginAttempts: 5},
+			`{}`,
+		},
+		{"both nil", nil, nil, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if !jsonEqual(t, got, tt.want) {
+				t.Errorf("Diff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffUnmarshalable(t *testing.T) {
+	if _, err := Diff(func() {}, nil); err == nil {
+		t.Error("Diff() of a func returned no error")
+	}
+}
+
+func jsonEqual(t *testing.T, got json.RawMessage, want string) bool {
+	t.Helper()
+	var a, b any
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+	ga, _ := json.Marshal(a)
+	gb, _ := json.Marshal(b)
+	return string(ga) == string(gb)
+}
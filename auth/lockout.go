@@ -0,0 +1,24 @@
+package auth
+
+import "time"
+
+// Lockout — политика прогрессивной блокировки входа: после Threshold неудач
+// подряд аккаунт блокируется на Delay, каждая следующая неудача удваивает срок,
+// но не больше MaxDelay. Threshold <= 0 отключает блокировку.
+type Lockout struct {
+	Threshold int
+	Delay     time.Duration
+	MaxDelay  time.Duration
+}
+
+// Duration возвращает срок блокировки после failures неудач подряд (0 — не блокировать)
+func (l Lockout) Duration(failures int) time.Duration {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return 0
+	}
+	d := l.Delay
+	for i := l.Threshold; i < failures && d < l.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, l.MaxDelay)
+}
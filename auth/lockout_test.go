@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	policy := Lockout{Threshold: 5, Delay: time.Minute, MaxDelay: time.Hour}
+
+	tests := []struct {
+		name     string
+		lockout  Lockout
+		failures int
+		want     time.Duration
+	}{
+		{"no failures", policy, 0, 0},
+		{"below threshold", policy, 4, 0},
+		{"at threshold", policy, 5, time.Minute},
+		{"doubles", policy, 6, 2 * time.Minute},
+		{"doubles again", policy, 8, 8 * time.Minute},
+		{"last step below max", policy, 10, 32 * time.Minute},
+		{"capped", policy, 11, time.Hour},
+		{"stays capped", policy, 1000, time.Hour},
+		{"disabled", Lockout{Threshold: 0, Delay: time.Minute, MaxDelay: time.Hour}, 100, 0},
+		{"negative threshold disables", Lockout{Threshold: -1, Delay: time.Minute, MaxDelay: time.Hour}, 100, 0},
+		{"delay above max", Lockout{Threshold: 1, Delay: 2 * time.Hour, MaxDelay: time.Hour}, 1, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lockout.Duration(tt.failures); got != tt.want {
+				t.Errorf("Duration(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
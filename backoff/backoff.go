@@ -0,0 +1,14 @@
+package backoff
+
+import "time"
+
+// Exponential возвращает задержку перед попыткой attempt+1: base·2^(attempt-1),
+// но не больше max. Удвоение останавливается на max, поэтому переполнения
+// не будет при любом attempt.
+func Exponential(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
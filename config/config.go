@@ -0,0 +1,415 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config — настройки приложения, читаются из переменных окружения
+type Config struct {
+	Log        LogConfig
+	Tracing    TracingConfig
+	HTTP       HTTPConfig
+	DB         DBConfig
+	UploadsDir string
+	// UploadsURL — база ссылок на загруженные файлы. Относительный путь
+	// дополняется схемой и хостом запроса, в котором файл был загружен, если
+	// этот origin есть в HTTP.PublicOrigins; иначе ссылка остаётся относительной.
+	UploadsURL string
+	UploadGC   UploadGCConfig
+	RateLimit  RateLimitConfig
+	CORS       CORSConfig
+	Security   SecurityConfig
+	Events     EventsConfig
+	WebSocket  WebSocketConfig
+	Webhooks   WebhooksConfig
+	Outbox     OutboxConfig
+	Trash      TrashConfig
+	Undo       UndoConfig
+}
+
+// LogConfig — формат (json/text) и уровень логов
+type LogConfig struct {
+	Format string
+	Level  string
+}
+
+// TracingConfig — экспорт трейсов OpenTelemetry
+type TracingConfig struct {
+	// Exporter — none, stdout или otlp
+	Exporter    string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64
+}
+
+// HTTPConfig — параметры HTTP-сервера
+type HTTPConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownTimeout — сколько ждать завершения активных запросов при остановке
+	ShutdownTimeout time.Duration
+	// ShutdownDrainDelay — пауза между переводом /readyz в «не готов» и закрытием
+	// listener'а, чтобы оркестратор успел убрать инстанс из балансировки
+	ShutdownDrainDelay time.Duration
+	// TrustProxy — сервис стоит за своим прокси: IP клиента берётся из
+	// X-Forwarded-For, схема — из X-Forwarded-Proto
+	TrustProxy bool
+	// PublicOrigins — origin'ы, по которым сервис доступен клиентам
+	// (https://api.example.com). Host и X-Forwarded-Host присылает клиент,
+	// поэтому origin запроса, которого нет в списке, не используется.
+	PublicOrigins []string
+	// MetricsAddr — внутренний listener для /metrics и /debug/vars, без TLS и
+	// авторизации (пусто — не запускать). На публичном адресе их нет.
+	MetricsAddr string
+
+	TLS TLSConfig
+}
+
+// TLSConfig — HTTPS без внешнего прокси. TLS включён, если заданы CertFile и KeyFile.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ReloadInterval — как часто проверять, не обновились ли файлы сертификата
+	ReloadInterval time.Duration
+	// ClientCAFile — CA для проверки клиентских сертификатов (mTLS)
+	ClientCAFile string
+	// ClientAuth — none, request, verify_if_given или require
+	ClientAuth string
+	// RedirectAddr — адрес HTTP-listener'а, перенаправляющего на HTTPS (пусто — не запускать)
+	RedirectAddr string
+}
+
+// Enabled сообщает, нужно ли обслуживать HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// DBConfig — подключение к PostgreSQL
+type DBConfig struct {
+	// URL — строка подключения из DATABASE_URL. Значения по умолчанию нет:
+	// без неё сервис не стартует
+	URL string
+	// QueryTimeout — предельное время одного запроса к БД
+	QueryTimeout time.Duration
+	// RequestTimeout — общее время всех запросов к БД в рамках одного HTTP-запроса
+	RequestTimeout time.Duration
+	// ConnectTimeout — сколько ждать доступности БД при старте
+	ConnectTimeout time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// UploadGCConfig — настройки сборщика осиротевших файлов в uploads
+type UploadGCConfig struct {
+	Enabled     bool
+	Interval    time.Duration
+	GracePeriod time.Duration
+	DryRun      bool
+}
+
+// RateLimitConfig — ограничение частоты запросов и защита входа от перебора
+type RateLimitConfig struct {
+	// Backend — memory (в пределах процесса) или postgres (общий для всех инстансов)
+	Backend string
+
+	// Лимиты вида «N запросов за окно»
+	LoginPerIP       int
+	LoginPerUsername int
+	LoginWindow      time.Duration
+	RegisterPerIP    int
+	RegisterWindow   time.Duration
+	APIPerUser       int
+	APIWindow        time.Duration
+
+	// LockoutThreshold — после скольких неудачных входов подряд блокировать аккаунт.
+	// Каждая следующая неудача удваивает блокировку, но не дольше LockoutMaxDelay.
+	LockoutThreshold int
+	LockoutDelay     time.Duration
+	LockoutMaxDelay  time.Duration
+}
+
+// CORSConfig — доступ к API из браузера с других origin'ов
+type CORSConfig struct {
+	// AllowedOrigins — список origin'ов; "*" разрешает любой. Пустой список выключает CORS.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge — сколько браузер может кэшировать ответ на preflight
+	MaxAge time.Duration
+}
+
+// SecurityConfig — защитные заголовки ответов
+type SecurityConfig struct {
+	ReferrerPolicy string
+	// UploadsCSP — Content-Security-Policy для отдаваемых загруженных файлов
+	UploadsCSP string
+	// HSTSMaxAge — max-age для Strict-Transport-Security (0 — не отправлять)
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+}
+
+// EventsConfig — рассылка изменений задач (SSE)
+type EventsConfig struct {
+	// Backend — memory (один инстанс) или postgres (LISTEN/NOTIFY между инстансами)
+	Backend string
+	// Channel — канал LISTEN/NOTIFY
+	Channel string
+	// LogSize — сколько последних событий хранить для продолжения по Last-Event-ID
+	LogSize int
+	// BufferSize — очередь одного подписчика; переполнивший её клиент отключается
+	BufferSize int
+	// Heartbeat — период пустых сообщений, не дающих прокси закрыть поток
+	Heartbeat time.Duration
+}
+
+// WebSocketConfig — двусторонняя синхронизация задач по WebSocket.
+// Допустимые Origin берутся из CORS_ALLOWED_ORIGINS.
+type WebSocketConfig struct {
+	// MessagesPerWindow сообщений клиента за MessageWindow на одно соединение
+	MessagesPerWindow int
+	MessageWindow     time.Duration
+	MaxMessageBytes   int64
+	// SendBuffer — очередь исходящих сообщений; клиент, переполнивший её, отключается
+	SendBuffer   int
+	PingInterval time.Duration
+}
+
+// WebhooksConfig — исходящие вебхуки: очередь доставок в БД и фоновый отправитель
+type WebhooksConfig struct {
+	// Enabled — запускать отправитель в этом инстансе; очередь пополняется в любом случае
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+	// Timeout — ожидание ответа получателя на одну попытку
+	Timeout time.Duration
+	// MaxAttempts попыток, после чего доставка помечается dead
+	MaxAttempts int
+	// Паузы между попытками растут от BackoffBase вдвое до BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Lease — на сколько забранная доставка скрыта от других инстансов
+	Lease time.Duration
+	// AllowPrivate разрешает адреса в локальных и приватных сетях (для разработки)
+	AllowPrivate bool
+}
+
+// OutboxConfig — разбор outbox доменных событий. Relay просыпается по
+// уведомлению о новых событиях, Interval — запасной период опроса.
+type OutboxConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	Lease     time.Duration
+	// Повтор события, которое не удалось разослать: от RetryBase вдвое до RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Retention — сколько хранить обработанные события
+	Retention time.Duration
+}
+
+// TrashConfig — окончательное удаление задач из корзины по сроку хранения
+type TrashConfig struct {
+	// Retention — сколько задача лежит в корзине (0 — не удалять автоматически)
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// UndoConfig — отмена недавних изменений задач
+type UndoConfig struct {
+	// Window — сколько изменение можно отменить (0 — отмена выключена)
+	Window time.Duration
+	// Interval и BatchSize — очистка журнала с истёкшим окном
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Load читает конфигурацию из окружения, подставляя значения по умолчанию
+func Load() Config {
+	return Config{
+		Log: LogConfig{
+			Format: getEnv("LOG_FORMAT", "json"),
+			Level:  getEnv("LOG_LEVEL", "info"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			Insecure:    getBool("TRACING_INSECURE", true),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "todo-api"),
+			SampleRatio: getFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		HTTP: HTTPConfig{
+			Addr:               getEnv("HTTP_ADDR", ":8080"),
+			ReadTimeout:        getDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+			ReadHeaderTimeout:  getDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:       getDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:        getDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			MaxHeaderBytes:     getInt("HTTP_MAX_HEADER_BYTES", 1<<20),
+			ShutdownTimeout:    getDuration("HTTP_SHUTDOWN_TIMEOUT", 20*time.Second),
+			ShutdownDrainDelay: getDuration("HTTP_SHUTDOWN_DRAIN_DELAY", 0),
+			TrustProxy:         getBool("HTTP_TRUST_PROXY", false),
+			PublicOrigins:      getList("PUBLIC_ORIGINS", nil),
+			MetricsAddr:        getEnv("METRICS_ADDR", "127.0.0.1:9090"),
+			TLS: TLSConfig{
+				CertFile:       getEnv("TLS_CERT_FILE", ""),
+				KeyFile:        getEnv("TLS_KEY_FILE", ""),
+				ReloadInterval: getDuration("TLS_RELOAD_INTERVAL", 30*time.Second),
+				ClientCAFile:   getEnv("TLS_CLIENT_CA_FILE", ""),
+				ClientAuth:     getEnv("TLS_CLIENT_AUTH", "none"),
+				RedirectAddr:   getEnv("TLS_REDIRECT_ADDR", ""),
+			},
+		},
+		DB: DBConfig{
+			URL:             getEnv("DATABASE_URL", ""),
+			QueryTimeout:    getDuration("DB_QUERY_TIMEOUT", 5*time.Second),
+			RequestTimeout:  getDuration("DB_REQUEST_TIMEOUT", 10*time.Second),
+			ConnectTimeout:  getDuration("DB_CONNECT_TIMEOUT", time.Minute),
+			MaxOpenConns:    getInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getInt("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		},
+		UploadsDir: getEnv("UPLOADS_DIR", "./uploads"),
+		UploadsURL: getEnv("UPLOADS_URL", "/uploads/"),
+		UploadGC: UploadGCConfig{
+			Enabled:     getBool("UPLOAD_GC_ENABLED", true),
+			Interval:    getDuration("UPLOAD_GC_INTERVAL", time.Hour),
+			GracePeriod: getDuration("UPLOAD_GC_GRACE_PERIOD", 24*time.Hour),
+			DryRun:      getBool("UPLOAD_GC_DRY_RUN", false),
+		},
+		RateLimit: RateLimitConfig{
+			Backend:          getEnv("RATE_LIMIT_BACKEND", "memory"),
+			LoginPerIP:       getInt("RATE_LIMIT_LOGIN_PER_IP", 20),
+			LoginPerUsername: getInt("RATE_LIMIT_LOGIN_PER_USERNAME", 5),
+			LoginWindow:      getDuration("RATE_LIMIT_LOGIN_WINDOW", time.Minute),
+			RegisterPerIP:    getInt("RATE_LIMIT_REGISTER_PER_IP", 5),
+			RegisterWindow:   getDuration("RATE_LIMIT_REGISTER_WINDOW", time.Hour),
+			APIPerUser:       getInt("RATE_LIMIT_API_PER_USER", 300),
+			APIWindow:        getDuration("RATE_LIMIT_API_WINDOW", time.Minute),
+			LockoutThreshold: getInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutDelay:     getDuration("LOGIN_LOCKOUT_DELAY", time.Minute),
+			LockoutMaxDelay:  getDuration("LOGIN_LOCKOUT_MAX_DELAY", time.Hour),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getList("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods:   getList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE"}),
+			AllowedHeaders:   getList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Accept", "X-Request-ID"}),
+			ExposedHeaders:   getList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}),
+			AllowCredentials: getBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Events: EventsConfig{
+			Backend:    getEnv("EVENTS_BACKEND", "memory"),
+			Channel:    getEnv("EVENTS_CHANNEL", "todo_events"),
+			LogSize:    getInt("EVENTS_LOG_SIZE", 1000),
+			BufferSize: getInt("EVENTS_BUFFER_SIZE", 64),
+			Heartbeat:  getDuration("EVENTS_HEARTBEAT_INTERVAL", 15*time.Second),
+		},
+		WebSocket: WebSocketConfig{
+			MessagesPerWindow: getInt("WS_MESSAGES_PER_WINDOW", 20),
+			MessageWindow:     getDuration("WS_MESSAGE_WINDOW", 10*time.Second),
+			MaxMessageBytes:   int64(getInt("WS_MAX_MESSAGE_BYTES", 64<<10)),
+			SendBuffer:        getInt("WS_SEND_BUFFER", 64),
+			PingInterval:      getDuration("WS_PING_INTERVAL", 30*time.Second),
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      getBool("WEBHOOKS_ENABLED", true),
+			Interval:     getDuration("WEBHOOKS_INTERVAL", 5*time.Second),
+			BatchSize:    getInt("WEBHOOKS_BATCH_SIZE", 20),
+			Timeout:      getDuration("WEBHOOKS_TIMEOUT", 10*time.Second),
+			MaxAttempts:  getInt("WEBHOOKS_MAX_ATTEMPTS", 8),
+			BackoffBase:  getDuration("WEBHOOKS_BACKOFF_BASE", 30*time.Second),
+			BackoffMax:   getDuration("WEBHOOKS_BACKOFF_MAX", 6*time.Hour),
+			Lease:        getDuration("WEBHOOKS_LEASE", time.Minute),
+			AllowPrivate: getBool("WEBHOOKS_ALLOW_PRIVATE", false),
+		},
+		Outbox: OutboxConfig{
+			Interval:  getDuration("OUTBOX_INTERVAL", time.Second),
+			BatchSize: getInt("OUTBOX_BATCH_SIZE", 100),
+			Lease:     getDuration("OUTBOX_LEASE", 30*time.Second),
+			RetryBase: getDuration("OUTBOX_RETRY_BASE", time.Second),
+			RetryMax:  getDuration("OUTBOX_RETRY_MAX", time.Minute),
+			Retention: getDuration("OUTBOX_RETENTION", 24*time.Hour),
+		},
+		Trash: TrashConfig{
+			Retention: getDuration("TRASH_RETENTION", 30*24*time.Hour),
+			Interval:  getDuration("TRASH_PURGE_INTERVAL", time.Hour),
+			BatchSize: getInt("TRASH_PURGE_BATCH_SIZE", 100),
+		},
+		Undo: UndoConfig{
+			Window:    getDuration("UNDO_WINDOW", 10*time.Minute),
+			Interval:  getDuration("UNDO_EXPIRY_INTERVAL", time.Minute),
+			BatchSize: getInt("UNDO_EXPIRY_BATCH_SIZE", 500),
+		},
+		Security: SecurityConfig{
+			ReferrerPolicy:        getEnv("REFERRER_POLICY", "strict-origin-when-cross-origin"),
+			UploadsCSP:            getEnv("UPLOADS_CSP", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox"),
+			HSTSMaxAge:            getDuration("HSTS_MAX_AGE", 180*24*time.Hour),
+			HSTSIncludeSubdomains: getBool("HSTS_INCLUDE_SUBDOMAINS", false),
+		},
+	}
+}
+
+func getEnv(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// getList читает список через запятую; пустые элементы отбрасываются
+func getList(key string, def []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"todo-api/models"
+)
+
+func (s *queries) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, outcome, target_type, target_id, changes, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	changes := []byte(entry.Changes)
+	if len(changes) == 0 {
+		changes = []byte("{}")
+	}
+	_, err := s.db.ExecContext(ctx, query, entry.ActorID, entry.Action, entry.Outcome,
+		entry.TargetType, entry.TargetID, changes, entry.IP, entry.RequestID)
+	return mapError(err, "audit_entry", nil)
+}
+
+func (s *queries) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		where []string
+		args  []any
+	)
+	cond := func(sql string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(sql, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.ActorID != nil {
+		cond("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Subject != nil {
+		cond("(actor_id = ? OR (target_type = 'user' AND target_id = ?))", *filter.Subject)
+	}
+	if filter.Action != "" {
+		cond("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		cond("outcome = ?", filter.Outcome)
+	}
+	if filter.TargetType != "" {
+		cond("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		cond("target_id = ?", *filter.TargetID)
+	}
+	if filter.Since != nil {
+		cond("occurred_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		cond("occurred_at < ?", *filter.Until)
+	}
+
+	query := `SELECT id, occurred_at, actor_id, action, outcome, target_type, target_id, changes, ip, request_id FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	var entries []models.AuditEntry
+	if err := s.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, mapError(err, "audit_entry", nil)
+	}
+	return entries, nil
+}
@@ -2,20 +2,118 @@
 package db
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"todo-api/config"
+	"todo-api/store"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
+// querier — общее подмножество *sqlx.DB и *sqlx.Tx, через которое работают все запросы
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// queries реализует интерфейсы store поверх соединения или транзакции
+type queries struct {
+	db           querier
+	queryTimeout time.Duration
+}
+
 type PostgresStore struct {
-	DB *sqlx.DB
+	*queries
+	DB           *sqlx.DB
+	QueryTimeout time.Duration
 }
 
-func NewPostgresStore(connStr string) *PostgresStore {
-	db, err := sqlx.Connect("postgres", connStr)
+// NewPostgresStore открывает пул соединений и ждёт, пока Postgres станет доступен.
+// Пока не истечёт cfg.ConnectTimeout, попытки повторяются с экспоненциальной паузой:
+// так сервис переживает ситуацию, когда БД стартует позже него.
+func NewPostgresStore(ctx context.Context, cfg config.DBConfig) (*PostgresStore, error) {
+	// lib/pq с пустой строкой молча подключится к localhost
+	if cfg.URL == "" {
+		return nil, errors.New("DATABASE_URL is not set")
+	}
+	db, err := sqlx.Open("postgres", cfg.URL)
 	if err != nil {
-		log.Fatalf("❌ Failed to connect to DB: %v", err)
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := waitForDB(ctx, db, cfg.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresStore{
+		queries:      &queries{db: traced(db), queryTimeout: cfg.QueryTimeout},
+		DB:           db,
+		QueryTimeout: cfg.QueryTimeout,
+	}, nil
+}
+
+const (
+	connectInitialBackoff = 250 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
+func waitForDB(ctx context.Context, db *sqlx.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Warn("DB is not ready, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("connect to DB: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, connectMaxBackoff)
 	}
-	return &PostgresStore{DB: db}
+}
+
+// Ping проверяет, что БД отвечает
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.DB.PingContext(ctx)
+}
+
+// Close закрывает пул соединений
+func (s *PostgresStore) Close() error {
+	return s.DB.Close()
+}
+
+// PublishPoolStats публикует статистику пула соединений в expvar (/debug/vars)
+func (s *PostgresStore) PublishPoolStats(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return s.DB.Stats()
+	}))
+}
+
+// withTimeout накладывает на ctx общий таймаут запроса к БД
+func (s *queries) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return store.WithQueryTimeout(ctx, s.queryTimeout)
 }
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"todo-api/store"
+
+	"github.com/lib/pq"
+)
+
+// Коды ошибок PostgreSQL, которые превращаем в доменные ошибки
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqNotNullViolation    = "23502"
+	pqCheckViolation      = "23514"
+	pqQueryCanceled       = "57014"
+)
+
+// mapError переводит ошибки драйвера в типизированные ошибки пакета store
+func mapError(err error, entity string, id any) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return &store.NotFoundError{Entity: entity, ID: id}
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch string(pqErr.Code) {
+	case pqUniqueViolation:
+		return &store.ConflictError{Entity: entity, Field: constraintField(pqErr.Constraint), Reason: "already exists"}
+	case pqForeignKeyViolation:
+		return &store.ConflictError{Entity: entity, Field: constraintField(pqErr.Constraint), Reason: "references a missing or still referenced row"}
+	case pqNotNullViolation:
+		return store.NewValidationError(pqErr.Column, "required", pqErr.Column+" is required")
+	case pqQueryCanceled:
+		return fmt.Errorf("%w: %v", store.ErrCanceled, err)
+	case pqCheckViolation:
+		field := constraintField(pqErr.Constraint)
+		return store.NewValidationError(field, "invalid", field+" is invalid")
+	}
+	return err
+}
+
+// constraintField угадывает имя колонки по имени ограничения,
+// сгенерированному Postgres: <table>_<column>_key / _fkey / _check
+func constraintField(constraint string) string {
+	for _, suffix := range []string{"_key", "_fkey", "_check"} {
+		if name, ok := strings.CutSuffix(constraint, suffix); ok {
+			if _, column, ok := strings.Cut(name, "_"); ok {
+				return column
+			}
+			return name
+		}
+	}
+	return constraint
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"todo-api/models"
+	"todo-api/store"
+)
+
+func (s *queries) FilterTodos(ctx context.Context, userID int, filter models.TodoFilter, limit, offset int) ([]models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	todos := []models.Todo{}
+	where := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []any{userID}
+	cond := func(sql string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(sql, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.Done != nil {
+		cond("done = ?", *filter.Done)
+	}
+	if filter.HasPhoto != nil {
+		cond("(COALESCE(photo_url, '') <> '') = ?", *filter.HasPhoto)
+	}
+	if filter.Query != "" {
+		terms := searchTerms(filter.Query)
+		if len(terms) == 0 {
+			return todos, nil
+		}
+		cond("search_vector @@ to_tsquery('"+searchConfig+"', ?)", prefixQuery(terms))
+	}
+	if filter.CreatedAfter != nil {
+		cond("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		cond("created_at < ?", *filter.CreatedBefore)
+	}
+	// completed_at у невыполненных пуст и под сравнение не попадает
+	if filter.CompletedAfter != nil {
+		cond("completed_at >= ?", *filter.CompletedAfter)
+	}
+	if filter.CompletedBefore != nil {
+		cond("completed_at < ?", *filter.CompletedBefore)
+	}
+
+	// LIMIT NULL — без ограничения
+	var limitArg any
+	if limit > 0 {
+		limitArg = limit
+	}
+	args = append(args, limitArg, offset)
+	// photo_url как в GetTodos: у задач без фото — пустая строка
+	query := `SELECT id, title, done, user_id, COALESCE(photo_url, '') AS photo_url,
+                 created_at, updated_at, completed_at FROM todos
+          WHERE ` + strings.Join(where, " AND ") + `
+          ORDER BY id LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	err := s.db.SelectContext(ctx, &todos, query, args...)
+	return todos, mapError(err, "todo", nil)
+}
+
+// filterRow — сохранённый фильтр; условия хранятся в JSONB
+type filterRow struct {
+	models.SavedFilter
+	FilterJSON []byte `db:"filter"`
+}
+
+func (r filterRow) model() (models.SavedFilter, error) {
+	f := r.SavedFilter
+	if err := json.Unmarshal(r.FilterJSON, &f.Filter); err != nil {
+		return models.SavedFilter{}, fmt.Errorf("decode filter %d: %w", f.ID, err)
+	}
+	return f, nil
+}
+
+const filterColumns = `id, user_id, name, filter, created_at`
+
+func (s *queries) CreateFilter(ctx context.Context, filter models.SavedFilter) (models.SavedFilter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	conditions, err := json.Marshal(filter.Filter)
+	if err != nil {
+		return models.SavedFilter{}, err
+	}
+
+	var row filterRow
+	query := `INSERT INTO saved_filters (user_id, name, filter) VALUES ($1, $2, $3) RETURNING ` + filterColumns
+	if err := s.db.GetContext(ctx, &row, query, filter.UserID, filter.Name, conditions); err != nil {
+		return models.SavedFilter{}, mapError(err, "filter", nil)
+	}
+	return row.model()
+}
+
+func (s *queries) GetFilters(ctx context.Context, userID int) ([]models.SavedFilter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var rows []filterRow
+	query := `SELECT ` + filterColumns + ` FROM saved_filters WHERE user_id = $1 ORDER BY name, id`
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, mapError(err, "filter", nil)
+	}
+
+	filters := make([]models.SavedFilter, len(rows))
+	for i, row := range rows {
+		f, err := row.model()
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = f
+	}
+	return filters, nil
+}
+
+func (s *queries) GetFilterByID(ctx context.Context, id int) (models.SavedFilter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var row filterRow
+	query := `SELECT ` + filterColumns + ` FROM saved_filters WHERE id = $1`
+	if err := s.db.GetContext(ctx, &row, query, id); err != nil {
+		return models.SavedFilter{}, mapError(err, "filter", id)
+	}
+	return row.model()
+}
+
+func (s *queries) UpdateFilter(ctx context.Context, id int, filter models.SavedFilter) (models.SavedFilter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	conditions, err := json.Marshal(filter.Filter)
+	if err != nil {
+		return models.SavedFilter{}, err
+	}
+
+	var row filterRow
+	query := `UPDATE saved_filters SET name = $1, filter = $2 WHERE id = $3 RETURNING ` + filterColumns
+	if err := s.db.GetContext(ctx, &row, query, filter.Name, conditions, id); err != nil {
+		return models.SavedFilter{}, mapError(err, "filter", id)
+	}
+	return row.model()
+}
+
+func (s *queries) DeleteFilter(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM saved_filters WHERE id = $1`, id)
+	if err != nil {
+		return mapError(err, "filter", id)
+	}
+	count, _ := res.RowsAffected()
+	if count == 0 {
+		return &store.NotFoundError{Entity: "filter", ID: id}
+	}
+	return nil
+}
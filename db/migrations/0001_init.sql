@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS todos (
+    id        SERIAL PRIMARY KEY,
+    title     TEXT NOT NULL,
+    done      BOOLEAN NOT NULL DEFAULT FALSE,
+    user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    photo_url TEXT
+);
+
+CREATE INDEX IF NOT EXISTS todos_user_id_idx ON todos (user_id);
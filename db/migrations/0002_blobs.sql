@@ -0,0 +1,8 @@
+-- Загруженные файлы хранятся по SHA-256 содержимого, ref_count — число задач,
+-- которые на них ссылаются. Строку без ссылок удаляет сборщик вместе с файлом.
+CREATE TABLE IF NOT EXISTS blobs (
+    key        TEXT PRIMARY KEY,
+    size       BIGINT NOT NULL,
+    ref_count  INTEGER NOT NULL DEFAULT 0 CHECK (ref_count >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,32 @@
+-- Outbox доменных событий: строка пишется тем же запросом, что и изменение,
+-- и разносится подписчикам фоновым relay. aggregate_* задают порядок:
+-- события одной сущности доставляются строго по возрастанию id.
+-- user_id без внешнего ключа — user.deleted переживает удаление пользователя.
+CREATE TABLE IF NOT EXISTS outbox (
+    id             BIGSERIAL PRIMARY KEY,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id   INTEGER NOT NULL,
+    event_type     TEXT NOT NULL,
+    user_id        INTEGER NOT NULL,
+    payload        JSONB NOT NULL,
+    occurred_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    locked_until   TIMESTAMPTZ,
+    last_error     TEXT,
+    processed_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (aggregate_type, aggregate_id, id) WHERE processed_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_processed_idx ON outbox (processed_at) WHERE processed_at IS NOT NULL;
+
+-- Будим relay сразу после коммита, не дожидаясь очередного опроса
+CREATE OR REPLACE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_notify ON outbox;
+CREATE TRIGGER outbox_notify AFTER INSERT ON outbox
+    FOR EACH STATEMENT EXECUTE FUNCTION outbox_notify();
@@ -0,0 +1,5 @@
+-- Корзина: удалённая задача хранит момент удаления и скрыта из обычных выборок,
+-- пока её не восстановят или не удалят окончательно
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS todos_trash_idx ON todos (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Журнал операций над задачами для отмены. before/after — поля задачи до
+-- и после операции: отмена возвращает before, если задача всё ещё в
+-- состоянии after. holds_photo — запись держит ссылку на старое фото
+-- (blobs.ref_count), пока операцию можно отменить.
+CREATE TABLE IF NOT EXISTS todo_operations (
+    id          BIGSERIAL PRIMARY KEY,
+    token       TEXT NOT NULL UNIQUE,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    todo_id     INTEGER NOT NULL,
+    op          TEXT NOT NULL CHECK (op IN ('create', 'update', 'delete', 'restore')),
+    before      JSONB,
+    after       JSONB NOT NULL,
+    holds_photo BOOLEAN NOT NULL DEFAULT false,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    undone_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS todo_operations_expires_idx ON todo_operations (expires_at);
+CREATE INDEX IF NOT EXISTS todo_operations_user_idx ON todo_operations (user_id);
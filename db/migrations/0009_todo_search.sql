@@ -0,0 +1,7 @@
+-- Полнотекстовый поиск по названиям задач. Конфигурация russian стеммит
+-- кириллицу русским стеммером, а латиницу — английским (english_stem),
+-- поэтому одного tsvector хватает и русским, и английским пользователям.
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('russian', title)) STORED;
+
+CREATE INDEX IF NOT EXISTS todos_search_idx ON todos USING GIN (search_vector);
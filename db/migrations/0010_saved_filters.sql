@@ -0,0 +1,10 @@
+-- Сохранённые фильтры («умные списки»): filter — models.TodoFilter в JSON.
+-- Имя ограничения выбрано так, чтобы constraintField вернул "name".
+CREATE TABLE IF NOT EXISTS saved_filters (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    filter     JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT filters_name_key UNIQUE (user_id, name)
+);
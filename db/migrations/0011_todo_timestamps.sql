@@ -0,0 +1,34 @@
+-- Время создания, последнего изменения и выполнения задачи — для фильтров
+-- по датам. Существующие задачи получают время применения миграции, а
+-- completed_at у них пуст: когда их выполнили, неизвестно.
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- updated_at меняется только при изменении полей задачи (не при переносе в
+-- корзину); completed_at ставится при переходе в выполненные и стирается
+-- при возврате в невыполненные
+CREATE OR REPLACE FUNCTION todos_touch() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        NEW.completed_at := CASE WHEN NEW.done THEN now() END;
+        RETURN NEW;
+    END IF;
+    IF (NEW.title, NEW.done, NEW.photo_url) IS DISTINCT FROM (OLD.title, OLD.done, OLD.photo_url) THEN
+        NEW.updated_at := now();
+    END IF;
+    IF NEW.done AND NOT OLD.done THEN
+        NEW.completed_at := now();
+    ELSIF NOT NEW.done THEN
+        NEW.completed_at := NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS todos_touch ON todos;
+CREATE TRIGGER todos_touch BEFORE INSERT OR UPDATE ON todos
+    FOR EACH ROW EXECUTE FUNCTION todos_touch();
+
+CREATE INDEX IF NOT EXISTS todos_created_idx ON todos (user_id, created_at);
+CREATE INDEX IF NOT EXISTS todos_completed_idx ON todos (user_id, completed_at) WHERE completed_at IS NOT NULL;
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notify отправляет уведомление в канал LISTEN/NOTIFY. Внутри транзакции
+// Postgres доставит его только после коммита.
+func (s *queries) Notify(ctx context.Context, channel, payload string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// Listen держит отдельное соединение с LISTEN channel и вызывает fn на каждое
+// уведомление до отмены ctx. Обрыв соединения переживает сам, но уведомления,
+// пришедшие за время обрыва, теряются.
+func Listen(ctx context.Context, url, channel string, fn func(payload string)) error {
+	listener := pq.NewListener(url, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("LISTEN connection lost", "channel", channel, "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("LISTEN connection restored", "channel", channel)
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("LISTEN reconnect failed", "channel", channel, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// nil приходит после переподключения
+			if n != nil {
+				fn(n.Extra)
+			}
+		case <-time.After(90 * time.Second):
+			// Проверяем, что соединение живо, даже если уведомлений нет
+			listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+)
+
+// OutboxChannel — канал LISTEN/NOTIFY, в который триггер сообщает о новых событиях
+const OutboxChannel = "outbox"
+
+// Выражения payload для событий outbox: те же поля, что отдаёт API
+const (
+	todoPayload = `json_build_object('id', t.id, 'title', t.title, 'done', t.done, 'user_id', t.user_id, 'photo_url', t.photo_url)`
+	userPayload = `json_build_object('id', u.id, 'username', u.username)`
+)
+
+func (s *queries) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// heads — самое раннее необработанное событие каждого агрегата. Аренда и
+	// processed_at проверяются по самой строке: после SKIP LOCKED Postgres
+	// перепроверит их на свежей версии, и чужая аренда не будет перехвачена.
+	query := `
+		WITH heads AS (
+			SELECT DISTINCT ON (aggregate_type, aggregate_id) id
+			FROM outbox WHERE processed_at IS NULL
+			ORDER BY aggregate_type, aggregate_id, id
+		), due AS (
+			SELECT o.id FROM outbox o JOIN heads h ON h.id = o.id
+			WHERE o.processed_at IS NULL AND (o.locked_until IS NULL OR o.locked_until <= now())
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE OF o SKIP LOCKED
+		), claimed AS (
+			UPDATE outbox o SET locked_until = now() + make_interval(secs => $2), attempts = o.attempts + 1
+			FROM due WHERE o.id = due.id
+			RETURNING o.*
+		)
+		SELECT id, aggregate_type, aggregate_id, event_type, user_id, payload, occurred_at, attempts, last_error
+		FROM claimed
+		ORDER BY id`
+
+	var due []models.OutboxEvent
+	if err := s.db.SelectContext(ctx, &due, query, limit, lease.Seconds()); err != nil {
+		return nil, mapError(err, "outbox_event", nil)
+	}
+	return due, nil
+}
+
+func (s *queries) MarkOutboxProcessed(ctx context.Context, id int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE outbox SET processed_at = now(), locked_until = NULL, last_error = NULL WHERE id = $1`, id)
+	return mapError(err, "outbox_event", id)
+}
+
+func (s *queries) RetryOutboxEvent(ctx context.Context, id int64, lastError string, retryAt time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE outbox SET locked_until = $2, last_error = $3 WHERE id = $1`, id, retryAt, lastError)
+	return mapError(err, "outbox_event", id)
+}
+
+func (s *queries) PruneOutbox(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM outbox WHERE processed_at < now() - make_interval(secs => $1)`, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
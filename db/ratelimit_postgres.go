@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// TakeToken реализует ratelimit.BucketStore: пополнение ведра и списание токена
+// делаются одним UPSERT'ом, поэтому параллельные запросы не обгоняют друг друга
+func (s *queries) TakeToken(ctx context.Context, key string, limit int, perSecond float64) (bool, float64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+			SELECT CASE WHEN b.refill >= 1 THEN b.refill - 1 ELSE b.refill END, b.refill >= 1, now()
+			FROM (
+				SELECT LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) AS refill
+			) b
+		)
+		RETURNING allowed, tokens`
+
+	var (
+		allowed bool
+		tokens  float64
+	)
+	if err := s.db.QueryRowContext(ctx, query, key, limit, perSecond).Scan(&allowed, &tokens); err != nil {
+		return false, 0, mapError(err, "rate_limit", key)
+	}
+	return allowed, tokens, nil
+}
+
+// PruneRateLimits удаляет вёдра, не тронутые дольше olderThan: к этому моменту
+// они гарантированно полны и ничем не отличаются от отсутствующих
+func (s *queries) PruneRateLimits(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE updated_at < now() - make_interval(secs => $1)`, olderThan.Seconds())
+	if err != nil {
+		return 0, mapError(err, "rate_limit", nil)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"todo-api/models"
+)
+
+// searchConfig — конфигурация полнотекстового поиска; должна совпадать с
+// выражением todos.search_vector из миграции 0009
+const searchConfig = "russian"
+
+// maxSearchTerms — сколько слов запроса учитывается
+const maxSearchTerms = 8
+
+// searchTerms разбивает запрос на слова из букв и цифр. Всё остальное,
+// в том числе операторы tsquery, считается разделителем, так что собранный
+// из слов запрос не может быть синтаксически неверным.
+func searchTerms(q string) []string {
+	terms := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// prefixQuery собирает tsquery, в котором каждое слово ищется по префиксу
+// и должны найтись все слова: "купить мол" → "купить:* & мол:*"
+func prefixQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+func (s *queries) SearchTodos(ctx context.Context, userID int, query string, limit, offset int) ([]models.TodoSearchResult, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	results := []models.TodoSearchResult{}
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	// Название экранируется до ts_headline: в выдаче HTML только от <mark>
+	sqlQuery := `
+		WITH q AS (SELECT to_tsquery('` + searchConfig + `', $2) AS query)
+		SELECT t.id, t.title, t.done, t.user_id, t.photo_url,
+		       ts_rank_cd(t.search_vector, q.query) AS rank,
+		       ts_headline('` + searchConfig + `',
+		           replace(replace(replace(t.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		           q.query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15') AS highlight
+		FROM todos t, q
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.search_vector @@ q.query
+		ORDER BY rank DESC, t.id DESC
+		LIMIT $3 OFFSET $4`
+	err := s.db.SelectContext(ctx, &results, sqlQuery, userID, prefixQuery(terms), limit, offset)
+	return results, mapError(err, "todo", nil)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"only separators", "  ,.!&|  ", nil},
+		{"words", "купить молоко", []string{"купить", "молоко"}},
+		{"digits kept", "2 литра", []string{"2", "литра"}},
+		{"punctuation splits", "молоко,хлеб;сыр", []string{"молоко", "хлеб", "сыр"}},
+		{"tsquery operators dropped", "мол:* & !хлеб | (сыр) <-> 'x'", []string{"мол", "хлеб", "сыр", "x"}},
+		{"hyphen splits", "e-mail", []string{"e", "mail"}},
+		{"mixed scripts", "report отчёт", []string{"report", "отчёт"}},
+		{
+			"capped at maxSearchTerms",
+			"a b c d e f g h i j",
+			[]string{"a", "b", "c", "d", "e", "f", "g", "h"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchTerms(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"мол"}, "мол:*"},
+		{[]string{"купить", "мол"}, "купить:* & мол:*"},
+		{searchTerms("мол:* & !хлеб"), "мол:* & хлеб:*"},
+	}
+	for _, tt := range tests {
+		if got := prefixQuery(tt.terms); got != tt.want {
+			t.Errorf("prefixQuery(%q) = %q, want %q", tt.terms, got, tt.want)
+		}
+	}
+}
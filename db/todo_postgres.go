@@ -2,15 +2,23 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"time"
+
+	"todo-api/events"
 	"todo-api/models"
+	"todo-api/store"
 )
 
-func (s *PostgresStore) GetTodos(userID int) ([]models.Todo, error) {
+func (s *queries) GetTodos(ctx context.Context, userID int) ([]models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	var todos []models.Todo
 	query := `SELECT id, title, done, user_id, COALESCE(photo_url, '') as photo_url
-          FROM todos WHERE user_id = $1 ORDER BY id`
-	err := s.DB.Select(&todos, query, userID)
+          FROM todos WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id`
+	err := s.db.SelectContext(ctx, &todos, query, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return []models.Todo{}, nil
@@ -20,43 +28,168 @@ func (s *PostgresStore) GetTodos(userID int) ([]models.Todo, error) {
 	return todos, nil
 }
 
-func (s *PostgresStore) CreateTodo(todo models.Todo) (models.Todo, error) {
-	query := `INSERT INTO todos (title, done, user_id, photo_url) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := s.DB.QueryRow(query, todo.Title, todo.Done, todo.UserID, todo.PhotoURL).Scan(&todo.ID)
-	return todo, err
+// Изменения задач пишут событие в outbox тем же запросом: событие
+// сохраняется тогда и только тогда, когда сохраняется само изменение.
+
+func (s *queries) CreateTodo(ctx context.Context, todo models.Todo) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH t AS (
+			INSERT INTO todos (title, done, user_id, photo_url) VALUES ($1, $2, $3, $4)
+			RETURNING id, title, done, user_id, photo_url
+		), o AS (
+			INSERT INTO outbox (aggregate_type, aggregate_id, event_type, user_id, payload)
+			SELECT $5, t.id, $6, t.user_id, ` + todoPayload + ` FROM t
+		)
+		SELECT id FROM t`
+	err := s.db.QueryRowContext(ctx, query, todo.Title, todo.Done, todo.UserID, todo.PhotoURL,
+		models.AggregateTodo, events.TodoCreated).Scan(&todo.ID)
+	return todo, mapError(err, "todo", nil)
 }
 
-func (s *PostgresStore) UpdateTodo(id int, updated models.Todo) (models.Todo, error) {
-	res, err := s.DB.Exec(
-		"UPDATE todos SET title=$1, done=$2, photo_url=$3 WHERE id=$4",
-		updated.Title, updated.Done, updated.PhotoURL, id,
-	)
+func (s *queries) UpdateTodo(ctx context.Context, id int, updated models.Todo) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// old видит строку до обновления: todo.completed пишется, только если
+	// задача перешла из невыполненных в выполненные
+	query := `
+		WITH old AS (
+			SELECT done FROM todos WHERE id = $4 AND deleted_at IS NULL
+		), t AS (
+			UPDATE todos SET title = $1, done = $2, photo_url = $3 WHERE id = $4 AND deleted_at IS NULL
+			RETURNING id, title, done, user_id, photo_url
+		), o AS (
+			INSERT INTO outbox (aggregate_type, aggregate_id, event_type, user_id, payload)
+			SELECT $5, t.id, e.type, t.user_id, ` + todoPayload + `
+			FROM t, old, (VALUES (1, $6::text), (2, $7::text)) AS e(ord, type)
+			WHERE e.ord = 1 OR (t.done AND NOT old.done)
+			ORDER BY e.ord
+		)
+		SELECT id, title, done, user_id, photo_url FROM t`
+
+	var todo models.Todo
+	err := s.db.QueryRowContext(ctx, query, updated.Title, updated.Done, updated.PhotoURL, id,
+		models.AggregateTodo, events.TodoUpdated, events.TodoCompleted,
+	).Scan(&todo.ID, &todo.Title, &todo.Done, &todo.UserID, &todo.PhotoURL)
 	if err != nil {
-		return models.Todo{}, err
+		return models.Todo{}, mapError(err, "todo", id)
+	}
+	return todo, nil
+}
+
+func (s *queries) DeleteTodo(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH t AS (
+			UPDATE todos SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, title, done, user_id, photo_url
+		), o AS (
+			INSERT INTO outbox (aggregate_type, aggregate_id, event_type, user_id, payload)
+			SELECT $2, t.id, $3, t.user_id, ` + todoPayload + ` FROM t
+		)
+		SELECT count(*) FROM t`
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, id, models.AggregateTodo, events.TodoDeleted).Scan(&count)
+	if err != nil {
+		return mapError(err, "todo", id)
 	}
-	count, _ := res.RowsAffected()
 	if count == 0 {
-		return models.Todo{}, sql.ErrNoRows
+		return &store.NotFoundError{Entity: "todo", ID: id}
 	}
-	updated.ID = id
-	return updated, nil
+	return nil
 }
 
-func (s *PostgresStore) DeleteTodo(id int) error {
-	res, err := s.DB.Exec("DELETE FROM todos WHERE id=$1", id)
+func (s *queries) GetTodoByID(ctx context.Context, id int) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var todo models.Todo
+	query := "SELECT id, title, done, user_id, photo_url FROM todos WHERE id = $1 AND deleted_at IS NULL"
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&todo.ID, &todo.Title, &todo.Done, &todo.UserID, &todo.PhotoURL)
+	return todo, mapError(err, "todo", id)
+}
+
+func (s *queries) LockTodo(ctx context.Context, id int) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var todo models.Todo
+	query := `SELECT id, title, done, user_id, photo_url, deleted_at FROM todos WHERE id = $1 FOR UPDATE`
+	err := s.db.GetContext(ctx, &todo, query, id)
+	return todo, mapError(err, "todo", id)
+}
+
+func (s *queries) GetTrash(ctx context.Context, userID int) ([]models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	todos := []models.Todo{}
+	query := `SELECT id, title, done, user_id, photo_url, deleted_at
+          FROM todos WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`
+	err := s.db.SelectContext(ctx, &todos, query, userID)
+	return todos, mapError(err, "todo", nil)
+}
+
+func (s *queries) GetDeletedTodo(ctx context.Context, id int) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var todo models.Todo
+	query := `SELECT id, title, done, user_id, photo_url, deleted_at
+          FROM todos WHERE id = $1 AND deleted_at IS NOT NULL`
+	err := s.db.GetContext(ctx, &todo, query, id)
+	return todo, mapError(err, "todo", id)
+}
+
+func (s *queries) RestoreTodo(ctx context.Context, id int) (models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH t AS (
+			UPDATE todos SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL
+			RETURNING id, title, done, user_id, photo_url
+		), o AS (
+			INSERT INTO outbox (aggregate_type, aggregate_id, event_type, user_id, payload)
+			SELECT $2, t.id, $3, t.user_id, ` + todoPayload + ` FROM t
+		)
+		SELECT id, title, done, user_id, photo_url FROM t`
+
+	var todo models.Todo
+	err := s.db.QueryRowContext(ctx, query, id, models.AggregateTodo, events.TodoRestored).
+		Scan(&todo.ID, &todo.Title, &todo.Done, &todo.UserID, &todo.PhotoURL)
+	return todo, mapError(err, "todo", id)
+}
+
+func (s *queries) PurgeTodo(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM todos WHERE id = $1 AND deleted_at IS NOT NULL", id)
 	if err != nil {
-		return err
+		return mapError(err, "todo", id)
 	}
 	count, _ := res.RowsAffected()
 	if count == 0 {
-		return sql.ErrNoRows
+		return &store.NotFoundError{Entity: "todo", ID: id}
 	}
 	return nil
 }
 
-func (s *PostgresStore) GetTodoByID(id int) (models.Todo, error) {
-	var todo models.Todo
-	query := "SELECT id, title, done, user_id, photo_url FROM todos WHERE id = $1"
-	err := s.DB.QueryRow(query, id).Scan(&todo.ID, &todo.Title, &todo.Done, &todo.UserID, &todo.PhotoURL)
-	return todo, err
+func (s *queries) GetExpiredTrash(ctx context.Context, olderThan time.Duration, limit int) ([]models.Todo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var todos []models.Todo
+	query := `SELECT id, title, done, user_id, photo_url, deleted_at
+          FROM todos WHERE deleted_at < now() - make_interval(secs => $1)
+          ORDER BY deleted_at LIMIT $2`
+	err := s.db.SelectContext(ctx, &todos, query, olderThan.Seconds(), limit)
+	return todos, mapError(err, "todo", nil)
 }
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"todo-api/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedQuerier открывает клиентский спан на каждый SQL-запрос
+// с текстом запроса в атрибуте db.query.text
+type tracedQuerier struct {
+	next querier
+}
+
+func traced(q querier) querier {
+	return tracedQuerier{next: q}
+}
+
+func (q tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	res, err := q.next.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return res, err
+}
+
+// QueryRowContext: ошибка станет известна только при Scan, поэтому спан
+// покрывает лишь отправку запроса
+func (q tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := q.next.QueryRowContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+func (q tracedQuerier) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span := startQuerySpan(ctx, query)
+	err := q.next.GetContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+func (q tracedQuerier) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span := startQuerySpan(ctx, query)
+	err := q.next.SelectContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return tracing.Tracer().Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
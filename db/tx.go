@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo-api/logging"
+	"todo-api/store"
+
+	"github.com/lib/pq"
+)
+
+const (
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+
+	txMaxAttempts = 5
+	txRetryDelay  = 10 * time.Millisecond
+)
+
+// WithTx выполняет fn в serializable-транзакции. Если Postgres сообщает о
+// конфликте сериализации или взаимной блокировке, транзакция повторяется
+// целиком (до txMaxAttempts раз) с нарастающей паузой.
+func (s *PostgresStore) WithTx(ctx context.Context, fn func(store.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= txMaxAttempts; attempt++ {
+		err = s.runTx(ctx, fn)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		logging.FromContext(ctx).Warn("transaction conflict, retrying",
+			"attempt", attempt, "max_attempts", txMaxAttempts, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+	return fmt.Errorf("transaction failed after %d attempts: %w", txMaxAttempts, err)
+}
+
+func (s *PostgresStore) runTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	// Транзакция целиком укладывается в общий дедлайн запроса
+	ctx, cancel := store.WithQueryTimeout(ctx, 0)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(&queries{db: traced(tx), queryTimeout: s.QueryTimeout}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isRetryable — ошибка означает, что транзакцию можно безопасно повторить
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"todo-api/store"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// txLog — драйвер database/sql без сервера: считает начатые, закоммиченные
+// и откаченные транзакции. Запросы внутри транзакции не нужны — WithTx
+// проверяется по тому, чем заканчивается каждая попытка.
+type txLog struct {
+	mu                        sync.Mutex
+	begun, commits, rollbacks int
+}
+
+func (l *txLog) Open(string) (driver.Conn, error) { return &fakeConn{log: l}, nil }
+
+func (l *txLog) counts() (begun, commits, rollbacks int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.begun, l.commits, l.rollbacks
+}
+
+type fakeConn struct{ log *txLog }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.log.mu.Lock()
+	defer c.log.mu.Unlock()
+	c.log.begun++
+	return fakeTx{log: c.log}, nil
+}
+
+type fakeTx struct{ log *txLog }
+
+func (t fakeTx) Commit() error {
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+	t.log.commits++
+	return nil
+}
+
+func (t fakeTx) Rollback() error {
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+	t.log.rollbacks++
+	return nil
+}
+
+type fakeConnector struct{ log *txLog }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.log.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.log }
+
+func newFakeStore(t *testing.T) (*PostgresStore, *txLog) {
+	t.Helper()
+	log := &txLog{}
+	db := sqlx.NewDb(sql.OpenDB(fakeConnector{log: log}), "postgres")
+	t.Cleanup(func() { db.Close() })
+	return &PostgresStore{DB: db}, log
+}
+
+func TestWithTx(t *testing.T) {
+	errFn := errors.New("fn failed")
+	serialization := &pq.Error{Code: pqSerializationFailure}
+	deadlock := &pq.Error{Code: pqDeadlockDetected}
+	uniqueViolation := &pq.Error{Code: "23505"}
+
+	tests := []struct {
+		name string
+		// errs — что вернёт fn на каждой попытке; последняя повторяется
+		errs      []error
+		wantErr   error
+		begun     int
+		commits   int
+		rollbacks int
+	}{
+		{name: "commit", errs: []error{nil}, begun: 1, commits: 1},
+		{name: "error rolls back", errs: []error{errFn}, wantErr: errFn, begun: 1, rollbacks: 1},
+		{name: "not retryable", errs: []error{uniqueViolation}, wantErr: uniqueViolation, begun: 1, rollbacks: 1},
+		{
+			name:  "serialization failure retried",
+			errs:  []error{serialization, deadlock, nil},
+			begun: 3, commits: 1, rollbacks: 2,
+		},
+		{
+			name:    "retries exhausted",
+			errs:    []error{serialization},
+			wantErr: serialization,
+			begun:   txMaxAttempts, rollbacks: txMaxAttempts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, log := newFakeStore(t)
+			attempt := 0
+			err := s.WithTx(context.Background(), func(store.Tx) error {
+				err := tt.errs[min(attempt, len(tt.errs)-1)]
+				attempt++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			begun, commits, rollbacks := log.counts()
+			if begun != tt.begun || commits != tt.commits || rollbacks != tt.rollbacks {
+				t.Errorf("begun/commits/rollbacks = %d/%d/%d, want %d/%d/%d",
+					begun, commits, rollbacks, tt.begun, tt.commits, tt.rollbacks)
+			}
+		})
+	}
+}
+
+func TestWithTxPanicRollsBack(t *testing.T) {
+	s, log := newFakeStore(t)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("recover() = %v, want the original panic", p)
+		}
+		begun, commits, rollbacks := log.counts()
+		if begun != 1 || commits != 0 || rollbacks != 1 {
+			t.Errorf("begun/commits/rollbacks = %d/%d/%d, want 1/0/1", begun, commits, rollbacks)
+		}
+	}()
+	s.WithTx(context.Background(), func(store.Tx) error { panic("boom") })
+}
+
+func TestWithTxCanceledDuringRetry(t *testing.T) {
+	s, log := newFakeStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := s.WithTx(ctx, func(store.Tx) error {
+		cancel()
+		return &pq.Error{Code: pqSerializationFailure}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithTx() error = %v, want context.Canceled", err)
+	}
+	if begun, _, rollbacks := log.counts(); begun != 1 || rollbacks != 1 {
+		t.Errorf("begun/rollbacks = %d/%d, want 1/1", begun, rollbacks)
+	}
+}
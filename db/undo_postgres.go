@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// operationRow — запись журнала отмены; снимки хранятся в JSONB
+type operationRow struct {
+	models.TodoOperation
+	BeforeJSON []byte `db:"before"`
+	AfterJSON  []byte `db:"after"`
+}
+
+func (r operationRow) model() (models.TodoOperation, error) {
+	op := r.TodoOperation
+	if r.BeforeJSON != nil {
+		op.Before = new(models.TodoSnapshot)
+		if err := json.Unmarshal(r.BeforeJSON, op.Before); err != nil {
+			return models.TodoOperation{}, fmt.Errorf("decode operation %d: %w", op.ID, err)
+		}
+	}
+	if err := json.Unmarshal(r.AfterJSON, &op.After); err != nil {
+		return models.TodoOperation{}, fmt.Errorf("decode operation %d: %w", op.ID, err)
+	}
+	return op, nil
+}
+
+func operationModels(rows []operationRow) ([]models.TodoOperation, error) {
+	ops := make([]models.TodoOperation, len(rows))
+	for i, row := range rows {
+		op, err := row.model()
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+const operationColumns = `id, token, user_id, todo_id, op, before, after, holds_photo, created_at, expires_at, undone_at`
+
+func (s *queries) RecordTodoOperation(ctx context.Context, op models.TodoOperation) (models.TodoOperation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var before []byte
+	if op.Before != nil {
+		b, err := json.Marshal(op.Before)
+		if err != nil {
+			return models.TodoOperation{}, err
+		}
+		before = b
+	}
+	after, err := json.Marshal(op.After)
+	if err != nil {
+		return models.TodoOperation{}, err
+	}
+
+	var row operationRow
+	query := `
+		INSERT INTO todo_operations (token, user_id, todo_id, op, before, after, holds_photo, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + operationColumns
+	err = s.db.GetContext(ctx, &row, query, op.Token, op.UserID, op.TodoID, op.Op,
+		before, after, op.HoldsPhoto, op.ExpiresAt)
+	if err != nil {
+		return models.TodoOperation{}, mapError(err, "operation", nil)
+	}
+	return row.model()
+}
+
+func (s *queries) GetTodoOperation(ctx context.Context, token string) (models.TodoOperation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var row operationRow
+	query := `SELECT ` + operationColumns + ` FROM todo_operations WHERE token = $1`
+	if err := s.db.GetContext(ctx, &row, query, token); err != nil {
+		return models.TodoOperation{}, mapError(err, "operation", nil)
+	}
+	return row.model()
+}
+
+func (s *queries) MarkTodoOperationUndone(ctx context.Context, id int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE todo_operations SET undone_at = now(), holds_photo = false WHERE id = $1 AND undone_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return mapError(err, "operation", id)
+	}
+	count, _ := res.RowsAffected()
+	if count == 0 {
+		return &store.ConflictError{Entity: "operation", Reason: "already undone"}
+	}
+	return nil
+}
+
+func (s *queries) TakeExpiredTodoOperations(ctx context.Context, limit int) ([]models.TodoOperation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var rows []operationRow
+	query := `
+		DELETE FROM todo_operations
+		WHERE id IN (
+			SELECT id FROM todo_operations
+			WHERE expires_at <= now()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + operationColumns
+	if err := s.db.SelectContext(ctx, &rows, query, limit); err != nil {
+		return nil, mapError(err, "operation", nil)
+	}
+	return operationModels(rows)
+}
+
+func (s *queries) DeleteUserTodoOperations(ctx context.Context, userID int) ([]models.TodoOperation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var rows []operationRow
+	query := `DELETE FROM todo_operations WHERE user_id = $1 RETURNING ` + operationColumns
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, mapError(err, "operation", nil)
+	}
+	return operationModels(rows)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+
+	"todo-api/models"
+)
+
+func (s *queries) GetPhotoURLs(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var urls []string
+	query := `SELECT photo_url FROM todos WHERE photo_url IS NOT NULL AND photo_url <> ''`
+	err := s.db.SelectContext(ctx, &urls, query)
+	return urls, err
+}
+
+func (s *queries) GetBlobKeys(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var keys []string
+	err := s.db.SelectContext(ctx, &keys, `SELECT key FROM blobs WHERE ref_count > 0`)
+	return keys, err
+}
+
+func (s *queries) AcquireBlob(ctx context.Context, key string, size int64) (models.Blob, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var blob models.Blob
+	query := `INSERT INTO blobs (key, size, ref_count) VALUES ($1, $2, 1)
+          ON CONFLICT (key) DO UPDATE SET ref_count = blobs.ref_count + 1
+          RETURNING key, size, ref_count, created_at`
+	err := s.db.GetContext(ctx, &blob, query, key, size)
+	return blob, mapError(err, "blob", key)
+}
+
+func (s *queries) ReleaseBlob(ctx context.Context, key string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Файлы, загруженные до учёта ссылок, записи не имеют — их найдёт сборщик
+	_, err := s.db.ExecContext(ctx, `UPDATE blobs SET ref_count = ref_count - 1 WHERE key = $1 AND ref_count > 0`, key)
+	return mapError(err, "blob", key)
+}
+
+func (s *queries) ClaimUnreferencedBlob(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// У осиротевшего файла может не быть записи; вставляем пустую, чтобы
+	// было что заблокировать. Параллельный AcquireBlob упрётся в эту строку.
+	_, err := s.db.ExecContext(ctx, `INSERT INTO blobs (key, size, ref_count) VALUES ($1, 0, 0) ON CONFLICT (key) DO NOTHING`, key)
+	if err != nil {
+		return false, mapError(err, "blob", key)
+	}
+
+	var refCount int
+	err = s.db.QueryRowContext(ctx, `SELECT ref_count FROM blobs WHERE key = $1 FOR UPDATE`, key).Scan(&refCount)
+	if err != nil {
+		return false, mapError(err, "blob", key)
+	}
+	if refCount > 0 {
+		return false, nil
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM blobs WHERE key = $1`, key); err != nil {
+		return false, mapError(err, "blob", key)
+	}
+	return true, nil
+}
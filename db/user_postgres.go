@@ -1,67 +1,161 @@
 package db
 
 import (
-	"database/sql"
+	"context"
+	"time"
+
+	"todo-api/events"
 	"todo-api/models"
+	"todo-api/store"
 )
 
-func (s *PostgresStore) GetUsers() ([]models.User, error) {
+func (s *queries) GetUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	var users []models.User
-	err := s.DB.Select(&users, "SELECT id, username, password_hash FROM users ORDER BY id")
+	err := s.db.SelectContext(ctx, &users, "SELECT id, username, password_hash, role FROM users ORDER BY id")
 	return users, err
 }
 
-func (s *PostgresStore) CreateUser(user models.User) (models.User, error) {
-	query := `INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id`
-	err := s.DB.QueryRow(query, user.Username, user.PasswordHash).Scan(&user.ID)
+func (s *queries) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id, role`
+	err := s.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash).Scan(&user.ID, &user.Role)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, mapError(err, "user", nil)
 	}
 	return user, nil
 }
 
-func (s *PostgresStore) UpdateUser(id int, updated models.User) (models.User, error) {
-	query := `UPDATE users SET username=$1, password_hash=$2 WHERE id=$3`
-	res, err := s.DB.Exec(query, updated.Username, updated.PasswordHash, id)
+func (s *queries) UpdateUsername(ctx context.Context, id int, username string) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	query := `UPDATE users SET username=$1 WHERE id=$2 RETURNING id, username, password_hash, role`
+	err := s.db.QueryRowContext(ctx, query, username, id).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	if err != nil {
+		return models.User{}, mapError(err, "user", id)
+	}
+	return user, nil
+}
+
+func (s *queries) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET password_hash=$1 WHERE id=$2`, passwordHash, id)
 	if err != nil {
-		return models.User{}, err
+		return mapError(err, "user", id)
 	}
 	count, _ := res.RowsAffected()
 	if count == 0 {
-		return models.User{}, sql.ErrNoRows
+		return &store.NotFoundError{Entity: "user", ID: id}
 	}
-	updated.ID = id
-	return updated, nil
+	return nil
 }
 
-func (s *PostgresStore) DeleteUser(id int) error {
-	res, err := s.DB.Exec("DELETE FROM users WHERE id = $1", id)
-	if err != nil {
-		return err
+func (s *queries) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Событие user.deleted пишется в outbox тем же запросом
+	query := `
+		WITH u AS (
+			DELETE FROM users WHERE id = $1
+			RETURNING id, username
+		), o AS (
+			INSERT INTO outbox (aggregate_type, aggregate_id, event_type, user_id, payload)
+			SELECT $2, u.id, $3, u.id, ` + userPayload + ` FROM u
+		)
+		SELECT count(*) FROM u`
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, id, models.AggregateUser, events.UserDeleted).Scan(&count); err != nil {
+		return mapError(err, "user", id)
 	}
-	count, _ := res.RowsAffected()
 	if count == 0 {
-		return sql.ErrNoRows
+		return &store.NotFoundError{Entity: "user", ID: id}
 	}
 	return nil
 }
 
-func (s *PostgresStore) GetUserByID(id int) (models.User, error) {
+func (s *queries) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	query := `SELECT id, username, password_hash, role, failed_login_attempts, locked_until FROM users WHERE id = $1`
+	err := s.db.GetContext(ctx, &user, query, id)
+	if err != nil {
+		return models.User{}, mapError(err, "user", id)
+	}
+	return user, nil
+}
+
+func (s *queries) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	query := `SELECT id, username, password_hash FROM users WHERE id = $1`
-	err := s.DB.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.PasswordHash)
+	query := `SELECT id, username, password_hash, role, failed_login_attempts, locked_until FROM users WHERE username = $1`
+	err := s.db.GetContext(ctx, &user, query, username)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, mapError(err, "user", username)
 	}
 	return user, nil
 }
 
-func (s *PostgresStore) GetByUsername(username string) (models.User, error) {
+func (s *queries) SetUserRole(ctx context.Context, id int, role string) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	query := `SELECT id, username, password_hash FROM users WHERE username = $1`
-	err := s.DB.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash)
+	query := `UPDATE users SET role = $1 WHERE id = $2 RETURNING id, username, role`
+	err := s.db.QueryRowContext(ctx, query, role, id).Scan(&user.ID, &user.Username, &user.Role)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, mapError(err, "user", id)
 	}
 	return user, nil
 }
+
+func (s *queries) RecordLoginFailure(ctx context.Context, id int) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var failures int
+	query := `UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE id = $1 RETURNING failed_login_attempts`
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&failures); err != nil {
+		return 0, mapError(err, "user", id)
+	}
+	return failures, nil
+}
+
+func (s *queries) LockUser(ctx context.Context, id int, until time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET locked_until = $1 WHERE id = $2`, until, id)
+	if err != nil {
+		return mapError(err, "user", id)
+	}
+	count, _ := res.RowsAffected()
+	if count == 0 {
+		return &store.NotFoundError{Entity: "user", ID: id}
+	}
+	return nil
+}
+
+func (s *queries) ResetLoginFailures(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		return mapError(err, "user", id)
+	}
+	return nil
+}
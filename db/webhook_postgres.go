@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+	"todo-api/store"
+
+	"github.com/lib/pq"
+)
+
+// webhookRow — строка webhooks: TEXT[] сканируется через pq.StringArray
+type webhookRow struct {
+	models.Webhook
+	Events pq.StringArray `db:"events"`
+}
+
+func (r webhookRow) model() models.Webhook {
+	hook := r.Webhook
+	hook.Events = r.Events
+	return hook
+}
+
+const webhookColumns = `id, user_id, url, events, secret, active, created_at`
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+func (s *queries) CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var row webhookRow
+	query := `INSERT INTO webhooks (user_id, url, events, secret, active) VALUES ($1, $2, $3, $4, $5)
+          RETURNING ` + webhookColumns
+	err := s.db.GetContext(ctx, &row, query, hook.UserID, hook.URL, pq.Array(hook.Events), hook.Secret, hook.Active)
+	if err != nil {
+		return models.Webhook{}, mapError(err, "webhook", nil)
+	}
+	return row.model(), nil
+}
+
+func (s *queries) GetWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var rows []webhookRow
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1 ORDER BY id`
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, mapError(err, "webhook", nil)
+	}
+
+	hooks := make([]models.Webhook, len(rows))
+	for i, row := range rows {
+		hooks[i] = row.model()
+	}
+	return hooks, nil
+}
+
+func (s *queries) GetWebhookByID(ctx context.Context, id int) (models.Webhook, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var row webhookRow
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	if err := s.db.GetContext(ctx, &row, query, id); err != nil {
+		return models.Webhook{}, mapError(err, "webhook", id)
+	}
+	return row.model(), nil
+}
+
+func (s *queries) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// История доставок удаляется вместе с вебхуком, в том числе ещё не отправленные
+	query := `WITH d AS (DELETE FROM webhook_deliveries WHERE webhook_id = $1)
+          DELETE FROM webhooks WHERE id = $1`
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return mapError(err, "webhook", id)
+	}
+	count, _ := res.RowsAffected()
+	if count == 0 {
+		return &store.NotFoundError{Entity: "webhook", ID: id}
+	}
+	return nil
+}
+
+func (s *queries) EnqueueWebhookDeliveries(ctx context.Context, userID int, event string, payload []byte) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO webhook_deliveries (webhook_id, url, secret, event, payload)
+          SELECT id, url, secret, $2, $3 FROM webhooks WHERE user_id = $1 AND active AND $2 = ANY(events)`
+	res, err := s.db.ExecContext(ctx, query, userID, event, string(payload))
+	if err != nil {
+		return 0, mapError(err, "webhook_delivery", nil)
+	}
+	return res.RowsAffected()
+}
+
+func (s *queries) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// SKIP LOCKED: несколько инстансов разбирают очередь, не мешая друг другу
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			LEFT JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND (w.id IS NULL OR w.active)
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2)
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+		       last_error, created_at, delivered_at, url, secret
+		FROM claimed
+		ORDER BY id`
+
+	var due []models.DueDelivery
+	if err := s.db.SelectContext(ctx, &due, query, limit, lease.Seconds()); err != nil {
+		return nil, mapError(err, "webhook_delivery", nil)
+	}
+	return due, nil
+}
+
+func (s *queries) RecordWebhookAttempt(ctx context.Context, id int64, attempt models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Состояние доставки и запись журнала меняются одним запросом
+	query := `
+		WITH d AS (
+			UPDATE webhook_deliveries SET
+				status = $2,
+				attempts = $3,
+				next_attempt_at = $4,
+				last_error = $5,
+				delivered_at = CASE WHEN $2 = 'succeeded' THEN now() END
+			WHERE id = $1
+			RETURNING id
+		)
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		SELECT id, $3, $6, $5, $7 FROM d`
+	res, err := s.db.ExecContext(ctx, query,
+		id, status, attempt.Attempt, nextAttemptAt, attempt.Error, attempt.StatusCode, attempt.DurationMS)
+	if err != nil {
+		return mapError(err, "webhook_delivery", id)
+	}
+	count, _ := res.RowsAffected()
+	if count == 0 {
+		return &store.NotFoundError{Entity: "webhook_delivery", ID: id}
+	}
+	return nil
+}
+
+func (s *queries) GetWebhookDeliveries(ctx context.Context, webhookID, limit, offset int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var deliveries []models.WebhookDelivery
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+          WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &deliveries, query, webhookID, limit, offset); err != nil {
+		return nil, mapError(err, "webhook_delivery", nil)
+	}
+	return deliveries, nil
+}
+
+func (s *queries) GetWebhookDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var delivery models.WebhookDelivery
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	if err := s.db.GetContext(ctx, &delivery, query, id); err != nil {
+		return models.WebhookDelivery{}, mapError(err, "webhook_delivery", id)
+	}
+
+	query = `SELECT attempt, status_code, error, duration_ms, attempted_at
+          FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`
+	if err := s.db.SelectContext(ctx, &delivery.Log, query, id); err != nil {
+		return models.WebhookDelivery{}, mapError(err, "webhook_delivery", id)
+	}
+	return delivery, nil
+}
+
+func (s *queries) RedeliverWebhook(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var delivery models.WebhookDelivery
+	query := `UPDATE webhook_deliveries
+          SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+          WHERE id = $1 RETURNING ` + deliveryColumns
+	if err := s.db.GetContext(ctx, &delivery, query, id); err != nil {
+		return models.WebhookDelivery{}, mapError(err, "webhook_delivery", id)
+	}
+	return delivery, nil
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+)
+
+// Типы событий
+const (
+	TodoCreated = "todo.created"
+	TodoUpdated = "todo.updated"
+	// TodoCompleted приходит вместе с TodoUpdated, когда задача отмечена выполненной
+	TodoCompleted = "todo.completed"
+	TodoDeleted   = "todo.deleted"
+	// TodoRestored — задача вернулась из корзины
+	TodoRestored = "todo.restored"
+	UserDeleted  = "user.deleted"
+)
+
+// Event — изменение задачи или пользователя. ID присваивает хаб при
+// публикации; события адресованы только своему пользователю (UserID).
+// Seq — номер события в outbox: доставка не менее одного раза, и повтор
+// приходит с тем же Seq.
+type Event struct {
+	ID     string       `json:"id,omitempty"`
+	Seq    int64        `json:"seq,omitempty"`
+	Type   string       `json:"type"`
+	UserID int          `json:"user_id"`
+	Todo   *models.Todo `json:"todo,omitempty"`
+	User   *models.User `json:"user,omitempty"`
+	At     time.Time    `json:"at"`
+}
+
+// Publisher рассылает события подписчикам. Relay повторяет событие, пока
+// Publish не вернёт nil, поэтому публикация должна переносить повторы.
+type Publisher interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+// NewTodoEvent собирает событие об изменении задачи
+func NewTodoEvent(typ string, todo models.Todo) Event {
+	return Event{Type: typ, UserID: todo.UserID, Todo: &todo, At: time.Now().UTC()}
+}
+
+// NewUserEvent собирает событие об изменении пользователя
+func NewUserEvent(typ string, user models.User) Event {
+	return Event{Type: typ, UserID: user.ID, User: &user, At: time.Now().UTC()}
+}
+
+// Multi рассылает событие всем publishers по очереди и возвращает все ошибки
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hub раздаёт события подписчикам внутри процесса и хранит последние
+// события в кольцевом журнале, чтобы переподключившийся клиент мог
+// дочитать пропущенное по Last-Event-ID.
+//
+// ID события — "<эпоха хаба>-<номер>": после перезапуска или при переходе
+// на другой инстанс эпоха не совпадёт, и клиент получит Reset вместо
+// молчаливой потери событий.
+type Hub struct {
+	epoch      string
+	bufferSize int
+
+	mu     sync.Mutex
+	seq    uint64
+	log    []Event // кольцевой буфер
+	next   int     // позиция для следующей записи в log
+	full   bool
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription — подписка на события одного пользователя. C закрывается,
+// когда подписчик не успевает читать (клиенту стоит переподключиться
+// с Last-Event-ID) или хаб остановлен.
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	userID int
+	hub    *Hub
+	once   sync.Once
+}
+
+// NewHub создаёт хаб с журналом на logSize событий; bufferSize — очередь
+// каждого подписчика
+func NewHub(logSize, bufferSize int) *Hub {
+	return &Hub{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		bufferSize: bufferSize,
+		log:        make([]Event, max(logSize, 1)),
+		subs:       map[*Subscription]struct{}{},
+	}
+}
+
+// Publish присваивает событию ID, пишет его в журнал и рассылает подписчикам.
+// Никогда не блокируется: медленный подписчик отключается.
+func (h *Hub) Publish(_ context.Context, ev Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	h.seq++
+	ev.ID = h.epoch + "-" + strconv.FormatUint(h.seq, 10)
+	h.log[h.next] = ev
+	h.next = (h.next + 1) % len(h.log)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	for sub := range h.subs {
+		if sub.userID != ev.UserID {
+			continue
+		}
+		select {
+		case sub.c <- ev:
+		default:
+			h.dropLocked(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe подписывает на события пользователя userID. Если передан
+// lastEventID, возвращает события из журнала после него; resumed == false
+// означает, что продолжить с этого места нельзя (журнал уже перезаписан
+// или ID от другого инстанса) и клиенту нужно перечитать список целиком.
+func (h *Hub) Subscribe(userID int, lastEventID string) (sub *Subscription, missed []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan Event, h.bufferSize)
+	sub = &Subscription{C: c, c: c, userID: userID, hub: h}
+	if h.closed {
+		close(c)
+		return sub, nil, true
+	}
+	h.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	missed, resumed = h.since(lastEventID, userID)
+	return sub, missed, resumed
+}
+
+// since возвращает события пользователя после события с ID lastID
+func (h *Hub) since(lastID string, userID int) ([]Event, bool) {
+	epoch, seqStr, ok := strings.Cut(lastID, "-")
+	if !ok || epoch != h.epoch {
+		return nil, false
+	}
+	last, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || last > h.seq {
+		return nil, false
+	}
+
+	size := uint64(h.next)
+	if h.full {
+		size = uint64(len(h.log))
+	}
+	// В журнале события с номерами (h.seq-size, h.seq]
+	if h.seq-last > size {
+		return nil, false
+	}
+
+	var missed []Event
+	for i := h.seq - last; i > 0; i-- {
+		idx := (h.next - int(i) + len(h.log)) % len(h.log)
+		if ev := h.log[idx]; ev.UserID == userID {
+			missed = append(missed, ev)
+		}
+	}
+	return missed, true
+}
+
+// Close отписывает всех: открытые потоки завершаются, не задерживая
+// остановку сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.dropLocked(sub)
+	}
+}
+
+func (h *Hub) dropLocked(sub *Subscription) {
+	delete(h.subs, sub)
+	sub.once.Do(func() { close(sub.c) })
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.dropLocked(s)
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// publishN публикует n событий; нечётные — пользователю 1, чётные — 2.
+// Номер события попадает в Seq, чтобы было видно, какие вернул since.
+func publishN(h *Hub, n int) {
+	for i := 1; i <= n; i++ {
+		h.Publish(context.Background(), Event{Seq: int64(i), Type: TodoUpdated, UserID: 2 - i%2})
+	}
+}
+
+func seqs(events []Event) []int64 {
+	var out []int64
+	for _, ev := range events {
+		out = append(out, ev.Seq)
+	}
+	return out
+}
+
+func TestHubSince(t *testing.T) {
+	const logSize = 4
+
+	tests := []struct {
+		name      string
+		published int
+		lastID    func(h *Hub) string
+		userID    int
+		want      []int64
+		resumed   bool
+	}{
+		{
+			name: "missed events of the user", published: 3, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch + "-0" },
+			want:   []int64{1, 3}, resumed: true,
+		},
+		{
+			name: "other user's events skipped", published: 3, userID: 2,
+			lastID: func(h *Hub) string { return h.epoch + "-1" },
+			want:   []int64{2}, resumed: true,
+		},
+		{
+			name: "up to date", published: 3, userID: 1,
+			lastID:  func(h *Hub) string { return h.epoch + "-3" },
+			resumed: true,
+		},
+		{
+			name: "after wraparound", published: 10, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch + "-7" },
+			want:   []int64{9}, resumed: true,
+		},
+		{
+			name: "whole log", published: 10, userID: 2,
+			lastID: func(h *Hub) string { return h.epoch + "-" + strconv.Itoa(10-logSize) },
+			want:   []int64{8, 10}, resumed: true,
+		},
+		{
+			name: "overwritten", published: 10, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch + "-" + strconv.Itoa(10-logSize-1) },
+		},
+		{
+			name: "other epoch", published: 3, userID: 1,
+			lastID: func(h *Hub) string { return "other-1" },
+		},
+		{
+			name: "from the future", published: 3, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch + "-4" },
+		},
+		{
+			name: "no dash", published: 3, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch },
+		},
+		{
+			name: "bad number", published: 3, userID: 1,
+			lastID: func(h *Hub) string { return h.epoch + "-x" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(logSize, 1)
+			publishN(h, tt.published)
+
+			missed, resumed := h.since(tt.lastID(h), tt.userID)
+			if resumed != tt.resumed {
+				t.Fatalf("resumed = %v, want %v", resumed, tt.resumed)
+			}
+			if got := seqs(missed); !slices.Equal(got, tt.want) {
+				t.Errorf("missed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubSubscribeResumes(t *testing.T) {
+	h := NewHub(8, 4)
+	publishN(h, 2)
+	first, _, _ := h.Subscribe(1, "")
+	publishN(h, 1)
+
+	ev := <-first.C
+	first.Close()
+	publishN(h, 3) // 1..3: пропущены события 1 и 3 пользователя 1
+
+	sub, missed, resumed := h.Subscribe(1, ev.ID)
+	defer sub.Close()
+	if !resumed {
+		t.Fatal("resumed = false, want true")
+	}
+	if got := seqs(missed); !slices.Equal(got, []int64{1, 3}) {
+		t.Errorf("missed = %v, want [1 3]", got)
+	}
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+)
+
+// Notifier отправляет уведомление в канал Postgres LISTEN/NOTIFY
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// PostgresBridge связывает хабы нескольких инстансов через LISTEN/NOTIFY:
+// Publish отправляет событие в канал, а Receive (подключается к db.Listen)
+// передаёт пришедшие из канала события — в том числе свои — локальному хабу.
+type PostgresBridge struct {
+	Hub      *Hub
+	Notifier Notifier
+	Channel  string
+}
+
+func (b PostgresBridge) Publish(ctx context.Context, ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.Notifier.Notify(ctx, b.Channel, string(payload))
+}
+
+func (b PostgresBridge) Receive(payload string) {
+	var ev Event
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		slog.Warn("invalid event notification", "channel", b.Channel, "error", err)
+		return
+	}
+	b.Hub.Publish(context.Background(), ev)
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"todo-api/backoff"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// pruneInterval — как часто удалять обработанные события
+const pruneInterval = time.Hour
+
+// RelayOptions — настройки разбора outbox
+type RelayOptions struct {
+	// BatchSize — сколько событий брать за раз
+	BatchSize int
+	// Lease — на сколько взятое событие скрыто от других инстансов
+	Lease time.Duration
+	// Повтор после ошибки подписчика: RetryBase·2^(n-1), но не больше RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Retention — сколько хранить обработанные события (0 — не удалять)
+	Retention time.Duration
+}
+
+// Relay разносит события outbox подписчикам (Publisher). Событие отмечается
+// обработанным только после успешной публикации, поэтому при сбое оно
+// придёт повторно — подписчики должны переносить дубликаты. События одного
+// агрегата идут строго по порядку: следующее не выдаётся, пока предыдущее
+// не обработано.
+type Relay struct {
+	store     store.OutboxStore
+	publisher Publisher
+	opts      RelayOptions
+	wake      chan struct{}
+}
+
+func NewRelay(store store.OutboxStore, publisher Publisher, opts RelayOptions) *Relay {
+	return &Relay{store: store, publisher: publisher, opts: opts, wake: make(chan struct{}, 1)}
+}
+
+// Wake просит разобрать очередь, не дожидаясь очередного опроса
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunOnce разносит одну пачку событий и возвращает их число
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	due, err := r.store.ClaimOutboxEvents(ctx, r.opts.BatchSize, r.opts.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim outbox events: %w", err)
+	}
+	for _, o := range due {
+		r.dispatch(ctx, o)
+	}
+	return len(due), nil
+}
+
+// Run разбирает outbox каждые interval и по Wake до отмены ctx. Пока
+// события есть, следующая пачка берётся сразу.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("outbox relay started", "interval", interval, "batch_size", r.opts.BatchSize)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastPrune := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-r.wake:
+			timer.Stop()
+		}
+
+		n, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("outbox relay failed", "error", err)
+		}
+		if r.opts.Retention > 0 && time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			r.prune(ctx)
+		}
+
+		if n > 0 && err == nil {
+			timer.Reset(0)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (r *Relay) dispatch(ctx context.Context, o models.OutboxEvent) {
+	logger := slog.With("outbox_id", o.ID, "event", o.EventType, "aggregate", o.AggregateType, "aggregate_id", o.AggregateID)
+
+	// Отметки outbox пишем на своём контексте: событие, которое подписчики
+	// уже получили, при остановке иначе осталось бы необработанным
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	ev, err := FromOutbox(o)
+	if err != nil {
+		// Повтор не поможет, а ждать будет весь агрегат — событие пропускаем
+		logger.Error("dropping malformed outbox event", "error", err)
+		if err := r.store.MarkOutboxProcessed(recordCtx, o.ID); err != nil {
+			logger.Error("failed to mark outbox event processed", "error", err)
+		}
+		return
+	}
+
+	err = r.publisher.Publish(ctx, ev)
+	if err != nil && ctx.Err() != nil {
+		// Публикацию прервала остановка — не ошибка подписчика, attempts не
+		// растёт. Событие снова выдаст ClaimOutboxEvents, когда истечёт Lease.
+		return
+	}
+	if err == nil {
+		if err := r.store.MarkOutboxProcessed(recordCtx, o.ID); err != nil {
+			logger.Error("failed to mark outbox event processed", "error", err)
+		}
+		return
+	}
+
+	retryAt := time.Now().Add(backoff.Exponential(o.Attempts, r.opts.RetryBase, r.opts.RetryMax))
+	logger.Warn("outbox event not delivered, will retry", "attempt", o.Attempts, "retry_at", retryAt, "error", err)
+	if err := r.store.RetryOutboxEvent(recordCtx, o.ID, err.Error(), retryAt); err != nil {
+		logger.Error("failed to reschedule outbox event", "error", err)
+	}
+}
+
+func (r *Relay) prune(ctx context.Context) {
+	n, err := r.store.PruneOutbox(ctx, r.opts.Retention)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to prune outbox", "error", err)
+		}
+		return
+	}
+	if n > 0 {
+		slog.Info("pruned outbox", "events", n)
+	}
+}
+
+// FromOutbox восстанавливает событие из строки outbox
+func FromOutbox(o models.OutboxEvent) (Event, error) {
+	ev := Event{Seq: o.ID, Type: o.EventType, UserID: o.UserID, At: o.OccurredAt.UTC()}
+	switch o.AggregateType {
+	case models.AggregateTodo:
+		ev.Todo = new(models.Todo)
+		if err := json.Unmarshal(o.Payload, ev.Todo); err != nil {
+			return Event{}, fmt.Errorf("decode todo payload: %w", err)
+		}
+	case models.AggregateUser:
+		ev.User = new(models.User)
+		if err := json.Unmarshal(o.Payload, ev.User); err != nil {
+			return Event{}, fmt.Errorf("decode user payload: %w", err)
+		}
+	default:
+		return Event{}, fmt.Errorf("unknown aggregate type %q", o.AggregateType)
+	}
+	return ev, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+
+	"todo-api/config"
+	"todo-api/db"
+	"todo-api/uploads"
+)
+
+// runUploadGC — одноразовый запуск сборщика осиротевших файлов из CLI
+func runUploadGC(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("gc-uploads", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", cfg.UploadGC.DryRun, "only report files that would be deleted")
+	grace := fs.Duration("grace", cfg.UploadGC.GracePeriod, "skip files younger than this")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	store, err := db.NewPostgresStore(ctx, cfg.DB)
+	if err != nil {
+		slog.Error("failed to connect to DB", "error", err)
+		return 1
+	}
+	defer store.Close()
+
+	if err := store.Migrate(ctx); err != nil {
+		slog.Error("failed to apply migrations", "error", err)
+		return 1
+	}
+
+	files := uploads.NewLocalStorage(cfg.UploadsDir, cfg.UploadsURL)
+	collector := uploads.NewCollector(files, store, store, *grace, *dryRun)
+
+	stats, err := collector.RunOnce(ctx)
+	if err != nil {
+		slog.Error("upload gc failed", "error", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(stats)
+	return 0
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/audit"
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+	"todo-api/validation"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditHandler struct {
+	Store store.AuditStore
+	Users store.UserStore
+}
+
+func NewAuditHandler(store store.AuditStore, users store.UserStore) *AuditHandler {
+	return &AuditHandler{Store: store, Users: users}
+}
+
+func (h *AuditHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/audit", h.GetAudit).Methods("GET")
+	r.HandleFunc("/api/me/activity", h.GetMyActivity).Methods("GET")
+}
+
+// recordAudit пишет запись журнала с изменениями между before и after
+func recordAudit(ctx context.Context, s store.AuditStore, entry models.AuditEntry, before, after any) error {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit diff: %w", err)
+	}
+	entry.Changes = changes
+	return s.RecordAudit(ctx, entry)
+}
+
+// callerID — пользователь из JWT для журнала аудита; 0, если токена нет
+func callerID(r *http.Request) int {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+// requireAdmin проверяет роль вызывающего по БД, а не по токену: снятая
+// роль перестаёт действовать сразу
+func (h *AuditHandler) requireAdmin(r *http.Request) error {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		return unauthorized("Unauthorized")
+	}
+	user, err := h.Users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return unauthorized("Unauthorized")
+		}
+		return err
+	}
+	if user.Role != models.RoleAdmin {
+		return forbidden("Admin role required")
+	}
+	return nil
+}
+
+// parseAuditFilter читает фильтры журнала из query
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	v := validation.New()
+	var filter models.AuditFilter
+
+	intParam := func(name string) *int {
+		s := q.Get(name)
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(s)
+		v.Check(err == nil, name, validation.CodeInvalidValue, name+" must be an integer")
+		return &n
+	}
+	timeParam := func(name string) *time.Time {
+		s := q.Get(name)
+		if s == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		v.Check(err == nil, name, validation.CodeInvalidValue, name+" must be an RFC 3339 timestamp")
+		return &t
+	}
+
+	filter.ActorID = intParam("actor_id")
+	filter.TargetID = intParam("target_id")
+	filter.Since = timeParam("since")
+	filter.Until = timeParam("until")
+
+	filter.Action = q.Get("action")
+	v.Check(filter.Action == "" || slices.Contains(audit.Actions, filter.Action), "action", validation.CodeInvalidValue,
+		"action must be one of: "+strings.Join(audit.Actions, ", "))
+	filter.Outcome = q.Get("outcome")
+	v.Check(filter.Outcome == "" || filter.Outcome == audit.Success || filter.Outcome == audit.Failure,
+		"outcome", validation.CodeInvalidValue, "outcome must be success or failure")
+	filter.TargetType = q.Get("target_type")
+	v.Check(filter.TargetType == "" || filter.TargetType == audit.TargetUser || filter.TargetType == audit.TargetTodo,
+		"target_type", validation.CodeInvalidValue, "target_type must be user or todo")
+
+	if err := v.Err(); err != nil {
+		return models.AuditFilter{}, err
+	}
+
+	p, err := parsePage(r, 50, 200)
+	if err != nil {
+		return models.AuditFilter{}, err
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+	return filter, nil
+}
+
+func (h *AuditHandler) list(w http.ResponseWriter, r *http.Request, filter models.AuditFilter) {
+	entries, err := h.Store.GetAuditEntries(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	writeGeneralResponse(w, "success", "Audit entries fetched", entries, http.StatusOK)
+}
+
+// GetAudit godoc
+// @Summary      Query the audit log
+// @Description  Журнал аудита всех пользователей, новые записи первыми. Только для администраторов.
+// @Tags         audit
+// @Produce      json
+// @Security     BearerAuth
+// @Param        actor_id     query     int     false  "Кто выполнил действие"
+// @Param        action       query     string  false  "Действие"  Enums(user.register, user.login, user.update, user.password_change, user.delete, todo.create, todo.update, todo.delete, todo.restore, todo.purge, todo.undo)
+// @Param        outcome      query     string  false  "Исход"  Enums(success, failure)
+// @Param        target_type  query     string  false  "Тип объекта"  Enums(user, todo)
+// @Param        target_id    query     int     false  "ID объекта"
+// @Param        since        query     string  false  "Не раньше (RFC 3339)"
+// @Param        until        query     string  false  "Раньше (RFC 3339)"
+// @Param        limit        query     int     false  "Размер страницы (1-200, по умолчанию 50)"
+// @Param        offset       query     int     false  "Смещение"
+// @Success      200          {object}  models.GeneralResponse{data=[]models.AuditEntry}
+// @Failure      401          {object}  models.GeneralResponse
+// @Failure      403          {object}  models.GeneralResponse
+// @Failure      422          {object}  models.GeneralResponse
+// @Failure      500          {object}  models.GeneralResponse
+// @Router       /audit [get]
+func (h *AuditHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	if err := h.requireAdmin(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.list(w, r, filter)
+}
+
+// GetMyActivity godoc
+// @Summary      Get own activity
+// @Description  Записи журнала аудита о действиях пользователя и над его аккаунтом (в том числе неудачные входы), новые первыми
+// @Tags         audit
+// @Produce      json
+// @Security     BearerAuth
+// @Param        action       query     string  false  "Действие"  Enums(user.register, user.login, user.update, user.password_change, user.delete, todo.create, todo.update, todo.delete, todo.restore, todo.purge, todo.undo)
+// @Param        outcome      query     string  false  "Исход"  Enums(success, failure)
+// @Param        target_type  query     string  false  "Тип объекта"  Enums(user, todo)
+// @Param        target_id    query     int     false  "ID объекта"
+// @Param        since        query     string  false  "Не раньше (RFC 3339)"
+// @Param        until        query     string  false  "Раньше (RFC 3339)"
+// @Param        limit        query     int     false  "Размер страницы (1-200, по умолчанию 50)"
+// @Param        offset       query     int     false  "Смещение"
+// @Success      200          {object}  models.GeneralResponse{data=[]models.AuditEntry}
+// @Failure      401          {object}  models.GeneralResponse
+// @Failure      422          {object}  models.GeneralResponse
+// @Failure      500          {object}  models.GeneralResponse
+// @Router       /me/activity [get]
+func (h *AuditHandler) GetMyActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Чужие действия недоступны: фильтр по действующему заменяется на «я»
+	filter.ActorID = nil
+	filter.Subject = &userID
+	h.list(w, r, filter)
+}
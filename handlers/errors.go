@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"todo-api/auth"
+	"todo-api/logging"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+const problemContentType = "application/problem+json"
+
+// httpError — ошибка уровня HTTP, не связанная с хранилищем (плохой JSON, нет токена и т.п.)
+type httpError struct {
+	Status  int
+	Message string
+}
+
+func (e *httpError) Error() string { return e.Message }
+
+func badRequest(message string) error {
+	return &httpError{Status: http.StatusBadRequest, Message: message}
+}
+func unauthorized(message string) error {
+	return &httpError{Status: http.StatusUnauthorized, Message: message}
+}
+func forbidden(message string) error {
+	return &httpError{Status: http.StatusForbidden, Message: message}
+}
+
+// requireSelf проверяет, что запрос сделан от имени пользователя с указанным ID
+func requireSelf(r *http.Request, userID int) error {
+	callerID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		return unauthorized("Unauthorized")
+	}
+	if callerID != userID {
+		return forbidden("You can only modify your own account")
+	}
+	return nil
+}
+
+// invalidID — ошибка разбора идентификатора из пути
+func invalidID() error {
+	return store.NewValidationError("id", "invalid", "id must be an integer")
+}
+
+// writeError отдаёт ошибку клиенту: как application/problem+json, если клиент
+// его просит, иначе в обычном конверте GeneralResponse. Причина внутренних
+// ошибок пишется в лог, клиент получает только безопасное сообщение.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFromError(err)
+	problem.Instance = r.URL.Path
+
+	logger := logging.FromContext(r.Context())
+	if problem.Status >= http.StatusInternalServerError {
+		logger.Error("request failed", "status", problem.Status, "error", err)
+	} else {
+		logger.Debug("request rejected", "status", problem.Status, "error", err)
+	}
+
+	if wantsProblemJSON(r) {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(problem.Status)
+		json.NewEncoder(w).Encode(problem)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(problem.Status)
+	resp := models.GeneralResponse{
+		Status:  "error",
+		Message: problem.Detail,
+	}
+	if len(problem.Errors) > 0 {
+		resp.Errors = problem.Errors
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// problemFromError сопоставляет доменные ошибки со статусами HTTP
+func problemFromError(err error) models.ProblemDetails {
+	var (
+		httpErr       *httpError
+		notFoundErr   *store.NotFoundError
+		conflictErr   *store.ConflictError
+		validationErr *store.ValidationError
+	)
+
+	switch {
+	case errors.As(err, &httpErr):
+		return newProblem(httpErr.Status, httpErr.Message)
+	case errors.As(err, &notFoundErr):
+		return newProblem(http.StatusNotFound, capitalize(notFoundErr.Entity)+" not found")
+	case errors.As(err, &conflictErr):
+		p := newProblem(http.StatusConflict, capitalize(conflictErr.Entity)+" conflicts with existing data")
+		if conflictErr.Field != "" {
+			p.Errors = []models.FieldError{{
+				Field:   conflictErr.Field,
+				Code:    "conflict",
+				Message: conflictErr.Field + " " + conflictErr.Reason,
+			}}
+		}
+		return p
+	case errors.Is(err, store.ErrCanceled), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return newProblem(http.StatusServiceUnavailable, "Request timed out")
+	case errors.As(err, &validationErr):
+		p := newProblem(http.StatusUnprocessableEntity, "Validation failed")
+		p.Errors = validationErr.Fields
+		return p
+	default:
+		return newProblem(http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+func newProblem(status int, detail string) models.ProblemDetails {
+	return models.ProblemDetails{
+		Type:   "/problems/" + strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "-"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// wantsProblemJSON — клиент явно указал application/problem+json в Accept
+func wantsProblemJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+	return false
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+	"todo-api/validation"
+
+	"github.com/gorilla/mux"
+)
+
+// FilterHandler — сохранённые фильтры задач («умные списки»)
+type FilterHandler struct {
+	Store store.FilterStore
+	Todos store.TodoStore
+}
+
+func NewFilterHandler(store store.FilterStore, todos store.TodoStore) *FilterHandler {
+	return &FilterHandler{Store: store, Todos: todos}
+}
+
+func (h *FilterHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/filters", h.CreateFilter).Methods("POST")
+	r.HandleFunc("/api/filters", h.GetFilters).Methods("GET")
+	r.HandleFunc("/api/filters/{id}", h.GetFilter).Methods("GET")
+	r.HandleFunc("/api/filters/{id}", h.UpdateFilter).Methods("PUT")
+	r.HandleFunc("/api/filters/{id}", h.DeleteFilter).Methods("DELETE")
+	r.HandleFunc("/api/filters/{id}/todos", h.GetFilterTodos).Methods("GET")
+}
+
+type filterInput struct {
+	Name   string            `json:"name" example:"Невыполненные покупки"`
+	Filter models.TodoFilter `json:"filter"`
+}
+
+// Validate проверяет имя и условия фильтра
+func (in filterInput) Validate() error {
+	v := validation.New()
+	v.Required("name", in.Name)
+	v.Length("name", in.Name, 1, 100)
+	v.Printable("name", in.Name)
+	v.Check(len([]rune(in.Filter.Query)) <= searchQueryMaxLength, "filter.query", validation.CodeTooLong,
+		"filter.query must be at most "+strconv.Itoa(searchQueryMaxLength)+" characters")
+	f := in.Filter
+	v.Check(f.CreatedAfter == nil || f.CreatedBefore == nil || f.CreatedAfter.Before(*f.CreatedBefore),
+		"filter.created_before", validation.CodeInvalidValue, "filter.created_before must be after filter.created_after")
+	v.Check(f.CompletedAfter == nil || f.CompletedBefore == nil || f.CompletedAfter.Before(*f.CompletedBefore),
+		"filter.completed_before", validation.CodeInvalidValue, "filter.completed_before must be after filter.completed_after")
+	return v.Err()
+}
+
+// decodeFilterInput читает тело запроса. Неизвестные поля — ошибка: опечатка
+// в условии иначе молча превратила бы фильтр в «все задачи».
+func decodeFilterInput(r *http.Request) (filterInput, error) {
+	var input filterInput
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		return filterInput{}, badRequest("Invalid JSON or unknown filter field")
+	}
+	return input, input.Validate()
+}
+
+// ownFilter загружает фильтр вызывающего пользователя; чужой выглядит как несуществующий
+func (h *FilterHandler) ownFilter(r *http.Request) (models.SavedFilter, error) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		return models.SavedFilter{}, unauthorized("Unauthorized")
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return models.SavedFilter{}, invalidID()
+	}
+
+	filter, err := h.Store.GetFilterByID(r.Context(), id)
+	if err != nil {
+		return models.SavedFilter{}, err
+	}
+	if filter.UserID != userID {
+		return models.SavedFilter{}, &store.NotFoundError{Entity: "filter", ID: id}
+	}
+	return filter, nil
+}
+
+// CreateFilter godoc
+// @Summary      Save a todo filter
+// @Description  Сохранить фильтр задач под именем. Задача подходит, если выполнены все заданные условия: done, has_photo, query (слова из названия, как в поиске), created_after/created_before и completed_after/completed_before (RFC 3339, полуинтервал [after, before)). Даты абсолютные, относительных периодов нет; приоритетов и сроков у задач нет, условий по ним тоже. Неизвестные поля отклоняются.
+// @Tags         filters
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        filter  body      filterInput  true  "Имя и условия"
+// @Success      201     {object}  models.GeneralResponse{data=models.SavedFilter}
+// @Failure      400     {object}  models.GeneralResponse
+// @Failure      401     {object}  models.GeneralResponse
+// @Failure      409     {object}  models.GeneralResponse
+// @Failure      422     {object}  models.GeneralResponse
+// @Router       /filters [post]
+func (h *FilterHandler) CreateFilter(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	input, err := decodeFilterInput(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	filter, err := h.Store.CreateFilter(r.Context(), models.SavedFilter{UserID: userID, Name: input.Name, Filter: input.Filter})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Filter saved", filter, http.StatusCreated)
+}
+
+// GetFilters godoc
+// @Summary      List saved filters
+// @Description  Сохранённые фильтры пользователя по имени
+// @Tags         filters
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.GeneralResponse{data=[]models.SavedFilter}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /filters [get]
+func (h *FilterHandler) GetFilters(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	filters, err := h.Store.GetFilters(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Filters fetched", filters, http.StatusOK)
+}
+
+// GetFilter godoc
+// @Summary      Get a saved filter
+// @Description  Сохранённый фильтр по ID
+// @Tags         filters
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Filter ID"
+// @Success      200  {object}  models.GeneralResponse{data=models.SavedFilter}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /filters/{id} [get]
+func (h *FilterHandler) GetFilter(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.ownFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Filter fetched", filter, http.StatusOK)
+}
+
+// UpdateFilter godoc
+// @Summary      Update a saved filter
+// @Description  Заменить имя и условия сохранённого фильтра
+// @Tags         filters
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int          true  "Filter ID"
+// @Param        filter  body      filterInput  true  "Имя и условия"
+// @Success      200     {object}  models.GeneralResponse{data=models.SavedFilter}
+// @Failure      400     {object}  models.GeneralResponse
+// @Failure      401     {object}  models.GeneralResponse
+// @Failure      404     {object}  models.GeneralResponse
+// @Failure      409     {object}  models.GeneralResponse
+// @Failure      422     {object}  models.GeneralResponse
+// @Router       /filters/{id} [put]
+func (h *FilterHandler) UpdateFilter(w http.ResponseWriter, r *http.Request) {
+	existing, err := h.ownFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	input, err := decodeFilterInput(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	filter, err := h.Store.UpdateFilter(r.Context(), existing.ID, models.SavedFilter{Name: input.Name, Filter: input.Filter})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Filter updated", filter, http.StatusOK)
+}
+
+// DeleteFilter godoc
+// @Summary      Delete a saved filter
+// @Description  Удалить сохранённый фильтр; задачи не затрагиваются
+// @Tags         filters
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Filter ID"
+// @Success      200  {object}  models.GeneralResponse
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /filters/{id} [delete]
+func (h *FilterHandler) DeleteFilter(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.ownFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.Store.DeleteFilter(r.Context(), filter.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Filter deleted", nil, http.StatusOK)
+}
+
+// GetFilterTodos godoc
+// @Summary      List todos matching a saved filter
+// @Description  Задачи под сохранённый фильтр. Постраничная выдача та же, что у GET /todos: без limit — все задачи, с limit — страница и meta.has_more.
+// @Tags         filters
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int  true   "Filter ID"
+// @Param        limit   query     int  false  "Сколько задач вернуть (1–500)"
+// @Param        offset  query     int  false  "Сколько задач пропустить"  default(0)
+// @Success      200  {object}  models.GeneralResponse{data=[]models.Todo,meta=models.PageMeta}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /filters/{id}/todos [get]
+func (h *FilterHandler) GetFilterTodos(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.ownFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	p, err := parsePage(r, 0, todoPageMaxLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	todos, meta, err := listTodos(r.Context(), h.Todos, filter.UserID, filter.Filter, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePagedResponse(w, "Todos fetched", todos, meta)
+}
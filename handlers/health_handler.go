@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"todo-api/logging"
+	"todo-api/models"
+	"todo-api/uploads"
+
+	"github.com/gorilla/mux"
+)
+
+// DBChecker — то, что нужно знать о БД для проверки готовности
+type DBChecker interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+type HealthHandler struct {
+	DB            DBChecker
+	Uploads       uploads.Storage
+	SchemaVersion int
+
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db DBChecker, files uploads.Storage, schemaVersion int) *HealthHandler {
+	return &HealthHandler{DB: db, Uploads: files, SchemaVersion: schemaVersion}
+}
+
+func (h *HealthHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/healthz", h.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", h.Readiness).Methods(http.MethodGet)
+}
+
+// SetShuttingDown переводит /readyz в состояние «не готов», чтобы балансировщик
+// перестал слать новые запросы, пока сервер дорабатывает текущие
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness — GET /healthz: процесс жив и обслуживает HTTP.
+// Маршрут вне /api, поэтому в Swagger не описывается.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeGeneralResponse(w, "success", "Alive", models.HealthReport{Status: "ok"}, http.StatusOK)
+}
+
+// Readiness — GET /readyz: проверка зависимостей (БД, хранилище файлов,
+// версия миграций). Отвечает 503, если что-то не в порядке или идёт остановка.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	report := models.HealthReport{
+		Status: "ok",
+		Checks: map[string]models.HealthCheck{
+			"database": runCheck(ctx, "database", func() (any, error) {
+				return nil, h.DB.Ping(ctx)
+			}),
+			"uploads": runCheck(ctx, "uploads", func() (any, error) {
+				return nil, h.Uploads.CheckWritable()
+			}),
+			// Схема новее ожидаемой — нормальное состояние при выкатке: новые
+			// инстансы уже применили миграции, а старые должны дорабатывать
+			"migrations": runCheck(ctx, "migrations", func() (any, error) {
+				version, err := h.DB.SchemaVersion(ctx)
+				details := map[string]int{"current": version, "expected": h.SchemaVersion}
+				if err == nil && version < h.SchemaVersion {
+					err = fmt.Errorf("schema version %d, expected at least %d", version, h.SchemaVersion)
+				}
+				return details, err
+			}),
+		},
+	}
+
+	if h.shuttingDown.Load() {
+		report.Checks["shutdown"] = models.HealthCheck{Status: "fail", Error: "server is shutting down"}
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+
+	if report.Status != "ok" {
+		writeGeneralResponse(w, "error", "Not ready", report, http.StatusServiceUnavailable)
+		return
+	}
+	writeGeneralResponse(w, "success", "Ready", report, http.StatusOK)
+}
+
+// runCheck выполняет проверку name. /readyz доступен без авторизации,
+// поэтому текст ошибки (адреса, пути, сообщения БД) только пишется в лог.
+func runCheck(ctx context.Context, name string, check func() (any, error)) models.HealthCheck {
+	start := time.Now()
+	details, err := check()
+	result := models.HealthCheck{
+		Status:    "ok",
+		LatencyMS: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = "unavailable"
+		logging.FromContext(ctx).Warn("readiness check failed", "check", name, "error", err)
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-api/uploads"
+)
+
+type fakeDB struct {
+	pingErr error
+	version int
+}
+
+func (db fakeDB) Ping(context.Context) error                 { return db.pingErr }
+func (db fakeDB) SchemaVersion(context.Context) (int, error) { return db.version, nil }
+
+type writableStorage struct{ uploads.Storage }
+
+func (writableStorage) CheckWritable() error { return nil }
+
+func TestReadiness(t *testing.T) {
+	const expected = 5
+	leak := errors.New(`dial tcp 10.0.0.7:5432: password authentication failed for user "app"`)
+
+	tests := []struct {
+		name   string
+		db     fakeDB
+		status int
+	}{
+		{"current schema", fakeDB{version: expected}, http.StatusOK},
+		{"newer schema during rollout", fakeDB{version: expected + 1}, http.StatusOK},
+		{"older schema", fakeDB{version: expected - 1}, http.StatusServiceUnavailable},
+		{"database down", fakeDB{version: expected, pingErr: leak}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(tt.db, writableStorage{}, expected)
+			rec := httptest.NewRecorder()
+			h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "10.0.0.7") || strings.Contains(body, "password") {
+				t.Errorf("error details leaked to /readyz: %s", body)
+			}
+		})
+	}
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	h := NewHealthHandler(fakeDB{version: 1}, writableStorage{}, 1)
+	h.SetShuttingDown()
+	rec := httptest.NewRecorder()
+	h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/logging"
+	"todo-api/models"
+	"todo-api/ratelimit"
+	"todo-api/store"
+)
+
+// LoginProtection — защита входа и регистрации от перебора.
+// Nil-лимитер означает, что соответствующее ограничение выключено.
+type LoginProtection struct {
+	LoginByIP       ratelimit.Limiter
+	LoginByUsername ratelimit.Limiter
+	RegisterByIP    ratelimit.Limiter
+	Lockout         auth.Lockout
+	// TrustProxy — брать IP клиента из X-Forwarded-For
+	TrustProxy bool
+}
+
+// errInvalidCredentials — единый ответ на любую неудачу входа: по нему нельзя
+// отличить несуществующего пользователя от неверного пароля или блокировки
+var errInvalidCredentials = unauthorized("Invalid username or password")
+
+// allow проверяет лимит и выставляет заголовки RateLimit-*. При отказе сразу
+// отвечает 429 и возвращает false. Сбой лимитера не блокирует вход: от перебора
+// в этом случае защищает блокировка аккаунта.
+func allow(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter, key string) bool {
+	if limiter == nil {
+		return true
+	}
+	res, err := limiter.Allow(r.Context(), key)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("rate limiter failed", "error", err)
+		return true
+	}
+	ratelimit.SetHeaders(w, res)
+	if !res.Allowed {
+		ratelimit.WriteTooManyRequests(w)
+		return false
+	}
+	return true
+}
+
+func (p LoginProtection) allowLogin(w http.ResponseWriter, r *http.Request, username string) bool {
+	return allow(w, r, p.LoginByIP, ratelimit.ClientIP(r, p.TrustProxy)) &&
+		allow(w, r, p.LoginByUsername, strings.ToLower(username))
+}
+
+func (p LoginProtection) allowRegister(w http.ResponseWriter, r *http.Request) bool {
+	return allow(w, r, p.RegisterByIP, ratelimit.ClientIP(r, p.TrustProxy))
+}
+
+// loginFailed учитывает неудачный вход и при необходимости блокирует аккаунт
+func (p LoginProtection) loginFailed(ctx context.Context, users store.UserStore, user models.User) error {
+	failures, err := users.RecordLoginFailure(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	d := p.Lockout.Duration(failures)
+	if d == 0 {
+		return nil
+	}
+	logging.FromContext(ctx).Warn("account locked", "user_id", user.ID, "failures", failures, "duration", d)
+	return users.LockUser(ctx, user.ID, time.Now().Add(d))
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"todo-api/models"
+	"todo-api/store"
+	"todo-api/validation"
+)
+
+// todoPageMaxLimit — наибольший limit списков задач. Без limit список
+// отдаётся целиком, как до появления постраничной выдачи.
+const todoPageMaxLimit = 500
+
+// page — параметры постраничной выдачи из query: ?limit=&offset=
+type page struct {
+	Limit  int
+	Offset int
+}
+
+// parsePage читает limit и offset; без limit используется defaultLimit,
+// больше maxLimit запросить нельзя
+func parsePage(r *http.Request, defaultLimit, maxLimit int) (page, error) {
+	q := r.URL.Query()
+	p := page{Limit: defaultLimit}
+	v := validation.New()
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		v.Check(err == nil && n >= 1 && n <= maxLimit, "limit", validation.CodeInvalidValue,
+			"limit must be an integer between 1 and "+strconv.Itoa(maxLimit))
+		p.Limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		v.Check(err == nil && n >= 0, "offset", validation.CodeInvalidValue, "offset must be a non-negative integer")
+		p.Offset = n
+	}
+	return p, v.Err()
+}
+
+// listTodos загружает страницу задач под filter. Запрашивается на одну
+// задачу больше, чтобы узнать, есть ли следующая страница.
+func listTodos(ctx context.Context, s store.TodoStore, userID int, filter models.TodoFilter, p page) ([]models.Todo, models.PageMeta, error) {
+	meta := models.PageMeta{Limit: p.Limit, Offset: p.Offset}
+	limit := p.Limit
+	if limit > 0 {
+		limit++
+	}
+
+	todos, err := s.FilterTodos(ctx, userID, filter, limit, p.Offset)
+	if err != nil {
+		return nil, meta, err
+	}
+	if p.Limit > 0 && len(todos) > p.Limit {
+		todos, meta.HasMore = todos[:p.Limit], true
+	}
+	return todos, meta, nil
+}
+
+// writePagedResponse — успешный ответ со страницей данных и её meta
+func writePagedResponse(w http.ResponseWriter, message string, data any, meta models.PageMeta) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if slice, ok := data.([]models.Todo); ok && slice == nil {
+		data = []models.Todo{}
+	}
+	json.NewEncoder(w).Encode(models.GeneralResponse{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"todo-api/tracing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassword хэширует пароль bcrypt'ом. Это самая дорогая операция в
+// регистрации, поэтому она выделена в отдельный спан.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.hash")
+	defer span.End()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword сверяет пароль с bcrypt-хэшем
+func checkPassword(ctx context.Context, hash, password string) error {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.compare")
+	defer span.End()
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// dummyHash — хэш, с которым сверяется пароль, когда пользователя нет или вход
+// заблокирован: время ответа не должно выдавать, существует ли аккаунт
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+})
+
+// checkDummyPassword тратит столько же времени, сколько checkPassword, и всегда неуспешна
+func checkDummyPassword(ctx context.Context, password string) {
+	_ = checkPassword(ctx, dummyHash(), password)
+}
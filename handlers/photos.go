@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"todo-api/logging"
+	"todo-api/metrics"
+	"todo-api/middleware"
+	"todo-api/store"
+	"todo-api/tracing"
+	"todo-api/uploads"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// photoRefs связывает файлы в хранилище с учётом ссылок на них в БД
+type photoRefs struct {
+	files uploads.Storage
+}
+
+// upload — файл, уже записанный в хранилище, но ещё не учтённый в БД
+type upload struct {
+	Key  string
+	Size int64
+}
+
+// save кладёт файл в хранилище. Ссылка на него учитывается позже,
+// в транзакции вместе с изменением задачи.
+func (p photoRefs) save(ctx context.Context, file io.Reader) (*upload, error) {
+	_, span := tracing.Tracer().Start(ctx, "uploads.save")
+	defer span.End()
+
+	key, size, err := p.files.Save(file)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("upload.key", key), attribute.Int64("upload.size", size))
+	metrics.Uploads.Inc()
+	metrics.UploadBytes.Add(float64(size))
+	return &upload{Key: key, Size: size}, nil
+}
+
+// errUploadLost — файл удалил сборщик между записью и учётом ссылки
+var errUploadLost = &httpError{Status: http.StatusConflict, Message: "Uploaded photo was removed, please upload it again"}
+
+// acquire учитывает ссылку на загруженный файл и возвращает его публичный URL.
+// Наличие файла проверяется после AcquireBlob: сборщик удаляет файлы под
+// блокировкой строки blobs, поэтому после учёта ссылки файл уже не пропадёт,
+// а удалённый до этого будет замечен здесь.
+func (p photoRefs) acquire(ctx context.Context, tx store.Tx, photo *upload) (*string, error) {
+	if photo == nil {
+		return nil, nil
+	}
+	if _, err := tx.AcquireBlob(ctx, photo.Key, photo.Size); err != nil {
+		return nil, err
+	}
+	exists, err := p.files.Exists(photo.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errUploadLost
+	}
+	url := absoluteURL(ctx, p.files.URL(photo.Key))
+	return &url, nil
+}
+
+// absoluteURL дополняет относительную ссылку origin'ом запроса, чтобы схема
+// (http/https) и хост совпадали с теми, по которым пришёл клиент. Origin не из
+// HTTP.PublicOrigins не известен: ссылка остаётся относительной, и клиентский
+// Host не попадает в БД.
+func absoluteURL(ctx context.Context, u string) string {
+	if !strings.HasPrefix(u, "/") || strings.HasPrefix(u, "//") {
+		return u
+	}
+	return middleware.GetOrigin(ctx) + u
+}
+
+// release снимает ссылку задачи на фото. Файл без ссылок удаляет только
+// сборщик осиротевших загрузок (uploads.Collector).
+func (p photoRefs) release(ctx context.Context, tx store.Tx, photoURL *string) error {
+	if photoURL == nil || *photoURL == "" {
+		return nil
+	}
+
+	key, ok := p.files.KeyFromURL(*photoURL)
+	if !ok {
+		logging.FromContext(ctx).Warn("invalid photo URL", "photo_url", *photoURL)
+		return nil
+	}
+	return tx.ReleaseBlob(ctx, key)
+}
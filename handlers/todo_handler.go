@@ -2,35 +2,51 @@ package handlers
 
 import (
 	"encoding/json"
-	"io"
-	"log"
+	"fmt"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"todo-api/auth"
 	"todo-api/models"
 	"todo-api/store"
+	"todo-api/uploads"
+	"todo-api/validation"
 
-	"path/filepath"
-
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type TodoHandler struct {
-	Store store.TodoStore
+	Store   store.TodoStore
+	Tx      store.Transactor
+	Uploads uploads.Storage
+	// Stream раздаёт изменения задач клиентам /todos/stream
+	Stream TodoStream
+	// UndoWindow — сколько изменение можно отменить (0 — отмена выключена)
+	UndoWindow time.Duration
+}
+
+func NewTodoHandler(store store.TodoStore, tx store.Transactor, files uploads.Storage, stream TodoStream, undoWindow time.Duration) *TodoHandler {
+	return &TodoHandler{Store: store, Tx: tx, Uploads: files, Stream: stream, UndoWindow: undoWindow}
 }
 
-func NewTodoHandler(store store.TodoStore) *TodoHandler {
-	return &TodoHandler{Store: store}
+func (h *TodoHandler) photos() photoRefs {
+	return photoRefs{files: h.Uploads}
 }
 
 func (h *TodoHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/todos", h.handleTodos).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/api/todos/stream", h.streamTodos).Methods(http.MethodGet)
+	// Поиск и корзина регистрируются раньше /api/todos/{id}, иначе "search"
+	// и "trash" примут за ID
+	r.HandleFunc("/api/todos/search", h.searchTodos).Methods(http.MethodGet)
+	r.HandleFunc("/api/todos/trash", h.getTrash).Methods(http.MethodGet)
+	r.HandleFunc("/api/todos/trash", h.emptyTrash).Methods(http.MethodDelete)
+	r.HandleFunc("/api/todos/trash/{id}", h.purgeTodo).Methods(http.MethodDelete)
+	r.HandleFunc("/api/todos/{id}/restore", h.restoreTodo).Methods(http.MethodPost)
 	r.HandleFunc("/api/todos/{id}", h.handleTodoByID).Methods(http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/api/undo", h.undoOperation).Methods(http.MethodPost)
 }
 
 func (h *TodoHandler) handleTodos(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +64,7 @@ func (h *TodoHandler) handleTodoByID(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/todos/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Invalid ID", nil, http.StatusBadRequest)
+		writeError(w, r, invalidID())
 		return
 	}
 
@@ -58,7 +74,7 @@ func (h *TodoHandler) handleTodoByID(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPut:
 		h.updateTodo(w, r, id)
 	case http.MethodDelete:
-		h.deleteTodo(w, id)
+		h.deleteTodo(w, r, id)
 	default:
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 	}
@@ -82,28 +98,50 @@ func writeGeneralResponse(w http.ResponseWriter, status, message string, data an
 	json.NewEncoder(w).Encode(resp)
 }
 
+// writeUndoableResponse — ответ на изменение задачи с токеном для его отмены
+func writeUndoableResponse(w http.ResponseWriter, message string, data any, undoToken string, httpStatus int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+
+	resp := models.GeneralResponse{
+		Status:    "success",
+		Message:   message,
+		Data:      data,
+		UndoToken: undoToken,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
 // @Summary      Get all todos
-// @Description  Получить список всех задач
+// @Description  Получить список задач. Без limit возвращаются все задачи; с limit — страница, а meta.has_more говорит, есть ли следующая.
 // @Tags         todos
 // @Produce      json
-// @Success      200  {object}  models.GeneralResponse{data=[]models.Todo}
+// @Param        limit   query     int  false  "Сколько задач вернуть (1–500)"
+// @Param        offset  query     int  false  "Сколько задач пропустить"  default(0)
+// @Success      200  {object}  models.GeneralResponse{data=[]models.Todo,meta=models.PageMeta}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
 // @Failure      500  {object}  models.GeneralResponse
 // @Router       /todos [get]
 func (h *TodoHandler) getTodos(w http.ResponseWriter, r *http.Request) {
 	claims, err := auth.ExtractClaimsFromRequest(r)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Unauthorized", nil, http.StatusUnauthorized)
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	p, err := parsePage(r, 0, todoPageMaxLimit)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	todos, err := h.Store.GetTodos(claims.UserID)
+	todos, meta, err := listTodos(r.Context(), h.Store, claims.UserID, models.TodoFilter{}, p)
 	if err != nil {
-		log.Printf("❌ Failed to fetch todos from DB: %v", err)
-		writeGeneralResponse(w, "error", "Failed to fetch todos", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("fetch todos: %w", err))
 		return
 	}
 
-	writeGeneralResponse(w, "success", "Todos fetched", todos, http.StatusOK)
+	writePagedResponse(w, "Todos fetched", todos, meta)
 }
 
 // @Summary      Create a new todo
@@ -114,13 +152,15 @@ func (h *TodoHandler) getTodos(w http.ResponseWriter, r *http.Request) {
 // @Param        todo  body      models.Todo        true  "Todo data"
 // @Success      201   {object}  models.GeneralResponse{data=models.Todo}
 // @Failure      400   {object}  models.GeneralResponse
+// @Failure      401   {object}  models.GeneralResponse
+// @Failure      409   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
 // @Failure      500   {object}  models.GeneralResponse
 // @Router       /todos [post]
 func (h *TodoHandler) createTodo(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseMultipartForm(10 << 20) // максимум 10MB
 	if err != nil {
-		log.Fatalf("❌ Failed to create uploads folder: %v", err)
-		writeGeneralResponse(w, "error", "Failed to parse form", nil, http.StatusBadRequest)
+		writeError(w, r, badRequest("Failed to parse form"))
 		return
 	}
 
@@ -128,53 +168,38 @@ func (h *TodoHandler) createTodo(w http.ResponseWriter, r *http.Request) {
 	doneStr := r.FormValue("done")
 	done := doneStr == "true" || doneStr == "1"
 
+	// Получить userID из токена (пример)
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	if err := validation.Todo(models.Todo{Title: title, Done: done}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
 	// Получаем файл
-	file, handler, err := r.FormFile("photo")
-	var photoURL *string
+	file, _, err := r.FormFile("photo")
+	var photo *upload
 	if err == nil {
 		defer file.Close()
 
-		ext := filepath.Ext(handler.Filename)
-		filename := uuid.New().String() + ext
-		filePath := "./uploads/" + filename
-
-		outFile, err := os.Create(filePath)
+		photo, err = h.photos().save(r.Context(), file)
 		if err != nil {
-			writeGeneralResponse(w, "error", "Failed to save file", nil, http.StatusInternalServerError)
+			writeError(w, r, fmt.Errorf("save photo: %w", err))
 			return
 		}
-		defer outFile.Close()
-
-		_, err = io.Copy(outFile, file)
-		if err != nil {
-			writeGeneralResponse(w, "error", "Failed to save file content", nil, http.StatusInternalServerError)
-			return
-		}
-
-		url := "http://localhost:8080/uploads/" + filename
-		photoURL = &url
 	}
 
-	// Получить userID из токена (пример)
-	userID, err := auth.ExtractUserIDFromRequest(r)
-	if err != nil {
-		writeGeneralResponse(w, "error", "Unauthorized", nil, http.StatusUnauthorized)
-		return
-	}
-
-	todo := models.Todo{
-		Title:    title,
-		Done:     done,
-		UserID:   userID,
-		PhotoURL: photoURL,
-	}
-
-	created, err := h.Store.CreateTodo(todo)
+	created, undoToken, err := h.create(r.Context(), models.Todo{Title: title, Done: done, UserID: userID}, photo)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to create todo", nil, http.StatusInternalServerError)
+		// Записанный файл подберёт сборщик осиротевших загрузок
+		writeError(w, r, err)
 		return
 	}
-	writeGeneralResponse(w, "success", "Todo created", created, http.StatusCreated)
+	writeUndoableResponse(w, "Todo created", created, undoToken, http.StatusCreated)
 }
 
 // @Summary      Update a todo by ID
@@ -186,12 +211,21 @@ func (h *TodoHandler) createTodo(w http.ResponseWriter, r *http.Request) {
 // @Param        todo  body      models.Todo   true  "Updated todo data"
 // @Success      200   {object}  models.GeneralResponse{data=models.Todo}
 // @Failure      400   {object}  models.GeneralResponse
+// @Failure      401   {object}  models.GeneralResponse
 // @Failure      404   {object}  models.GeneralResponse
+// @Failure      409   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
 // @Router       /todos/{id} [put]
 func (h *TodoHandler) updateTodo(w http.ResponseWriter, r *http.Request, id int) {
-	err := r.ParseMultipartForm(10 << 20)
+	userID, err := auth.ExtractUserIDFromRequest(r)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to parse form", nil, http.StatusBadRequest)
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		writeError(w, r, badRequest("Failed to parse form"))
 		return
 	}
 
@@ -199,91 +233,55 @@ func (h *TodoHandler) updateTodo(w http.ResponseWriter, r *http.Request, id int)
 	doneStr := r.FormValue("done")
 	done := doneStr == "true" || doneStr == "1"
 
-	existingTodo, err := h.Store.GetTodoByID(id)
-	if err != nil {
-		writeGeneralResponse(w, "error", "Todo not found", nil, http.StatusNotFound)
+	if err := validation.Todo(models.Todo{Title: title, Done: done}); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	photoURL := existingTodo.PhotoURL
-
-	file, handler, err := r.FormFile("photo")
+	file, _, err := r.FormFile("photo")
+	var photo *upload
 	if err == nil {
 		defer file.Close()
-		log.Printf("⚙️ Existing photoURL: %v", photoURL)
-
-		// Удаляем старый файл, если он есть
-		if photoURL != nil && *photoURL != "" {
-			parsedURL, err := url.Parse(*photoURL)
-			if err != nil {
-				log.Printf("❌ Invalid photo URL: %v", err)
-			} else {
-				// parsedURL.Path, например: /uploads/filename.jpg
-				filePath := "." + filepath.Clean(parsedURL.Path)
-
-				log.Printf("🗑️ Removing old photo file: %s", filePath)
-
-				err = os.Remove(filePath)
-				if err != nil && !os.IsNotExist(err) {
-					log.Printf("❌ Failed to delete old photo: %v", err)
-				} else {
-					log.Printf("✅ Old photo deleted")
-				}
-			}
-		}
 
-		ext := filepath.Ext(handler.Filename)
-		filename := uuid.New().String() + ext
-		filePath := "./uploads/" + filename
-
-		outFile, err := os.Create(filePath)
+		photo, err = h.photos().save(r.Context(), file)
 		if err != nil {
-			writeGeneralResponse(w, "error", "Failed to save file", nil, http.StatusInternalServerError)
+			writeError(w, r, fmt.Errorf("save photo: %w", err))
 			return
 		}
-		defer outFile.Close()
-
-		_, err = io.Copy(outFile, file)
-		if err != nil {
-			writeGeneralResponse(w, "error", "Failed to save file content", nil, http.StatusInternalServerError)
-			return
-		}
-
-		url := "http://localhost:8080/uploads/" + filename
-		photoURL = &url
-	}
-
-	updated := models.Todo{
-		Title:    title,
-		Done:     done,
-		PhotoURL: photoURL,
-		UserID:   existingTodo.UserID,
 	}
 
-	todo, err := h.Store.UpdateTodo(id, updated)
+	todo, undoToken, err := h.update(r.Context(), userID, id, models.Todo{Title: title, Done: done}, photo)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to update todo", nil, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
-
-	writeGeneralResponse(w, "success", "Todo updated", todo, http.StatusOK)
+	writeUndoableResponse(w, "Todo updated", todo, undoToken, http.StatusOK)
 }
 
 // @Summary      Delete a todo by ID
-// @Description  Удалить задачу по ID
+// @Description  Перенести задачу в корзину; восстановить её можно через POST /todos/{id}/restore или отменой по undo_token
 // @Tags         todos
 // @Produce      json
 // @Param        id   path      int  true  "Todo ID"
-// @Success      204  {object}  models.GeneralResponse "No Content"
+// @Success      200  {object}  models.GeneralResponse
+// @Failure      401  {object}  models.GeneralResponse
 // @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
 // @Router       /todos/{id} [delete]
-func (h *TodoHandler) deleteTodo(w http.ResponseWriter, id int) {
-	err := h.Store.DeleteTodo(id)
+func (h *TodoHandler) deleteTodo(w http.ResponseWriter, r *http.Request, id int) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	undoToken, err := h.remove(r.Context(), userID, id)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Todo not found", nil, http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
-	writeGeneralResponse(w, "success", "Todo deleted", nil, http.StatusNoContent)
+	// 200, а не 204: в теле ответа undo_token
+	writeUndoableResponse(w, "Todo deleted", nil, undoToken, http.StatusOK)
 }
 
 // @Summary      Get a todo by ID
@@ -294,10 +292,10 @@ func (h *TodoHandler) deleteTodo(w http.ResponseWriter, id int) {
 // @Success      200  {object}  models.GeneralResponse{data=models.Todo}
 // @Failure      404  {object}  models.GeneralResponse
 // @Router       /todos/{id} [get]
-func (h *TodoHandler) getTodoByID(w http.ResponseWriter, _ *http.Request, id int) {
-	todo, err := h.Store.GetTodoByID(id)
+func (h *TodoHandler) getTodoByID(w http.ResponseWriter, r *http.Request, id int) {
+	todo, err := h.Store.GetTodoByID(r.Context(), id)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Todo not found", nil, http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 	writeGeneralResponse(w, "success", "Todo fetched", todo, http.StatusOK)
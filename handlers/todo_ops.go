@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"todo-api/audit"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// Изменения задач, общие для REST и WebSocket. Входные данные к этому
+// моменту уже проверены, фото (если есть) записано в хранилище. actorID —
+// кто выполняет изменение: менять задачу может только её владелец, и
+// проверяется это здесь, под блокировкой строки задачи. События об
+// изменениях пишет в outbox само хранилище, рассылает их events.Relay.
+// Отменяемые операции возвращают undo_token (см. todo_undo.go); отменить
+// операцию может только тот, кто её выполнил.
+
+// systemActor — действующий фоновых задач (очистка корзины по сроку
+// хранения). Пользовательские операции с ним отклоняются.
+const systemActor = 0
+
+// lockTodo блокирует задачу до конца транзакции. deleted — ожидается ли
+// задача в корзине; задача в другом состоянии выглядит как несуществующая.
+func lockTodo(ctx context.Context, tx store.Tx, id int, deleted bool) (models.Todo, error) {
+	todo, err := tx.LockTodo(ctx, id)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	if (todo.DeletedAt != nil) != deleted {
+		return models.Todo{}, &store.NotFoundError{Entity: "todo", ID: id}
+	}
+	return todo, nil
+}
+
+// lockOwnTodo — lockTodo для задачи actorID; чужая выглядит как несуществующая
+func lockOwnTodo(ctx context.Context, tx store.Tx, actorID, id int, deleted bool) (models.Todo, error) {
+	if actorID == systemActor {
+		return models.Todo{}, unauthorized("Unauthorized")
+	}
+	todo, err := lockTodo(ctx, tx, id, deleted)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	if todo.UserID != actorID {
+		return models.Todo{}, &store.NotFoundError{Entity: "todo", ID: id}
+	}
+	return todo, nil
+}
+
+// create сохраняет задачу и учитывает ссылку на её фото
+func (h *TodoHandler) create(ctx context.Context, todo models.Todo, photo *upload) (models.Todo, string, error) {
+	var (
+		created   models.Todo
+		undoToken string
+	)
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		photoURL, err := h.photos().acquire(ctx, tx, photo)
+		if err != nil {
+			return err
+		}
+
+		todo.PhotoURL = photoURL
+		created, err = tx.CreateTodo(ctx, todo)
+		if err != nil {
+			return err
+		}
+		entry := audit.Entry(ctx, created.UserID, audit.TodoCreate, audit.TargetTodo, created.ID)
+		if err := recordAudit(ctx, tx, entry, nil, created); err != nil {
+			return err
+		}
+		undoToken, err = h.journal(ctx, tx, models.TodoOperation{
+			UserID: created.UserID,
+			TodoID: created.ID,
+			Op:     models.OpCreate,
+			After:  models.SnapshotOf(created),
+		})
+		return err
+	})
+	if err != nil {
+		return models.Todo{}, "", err
+	}
+	return created, undoToken, nil
+}
+
+// update меняет название и статус задачи; новое фото заменяет старое.
+// Пока операцию можно отменить, ссылку на старое фото держит журнал.
+func (h *TodoHandler) update(ctx context.Context, actorID, id int, in models.Todo, photo *upload) (models.Todo, string, error) {
+	var (
+		todo      models.Todo
+		undoToken string
+	)
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		existingTodo, err := lockOwnTodo(ctx, tx, actorID, id, false)
+		if err != nil {
+			return err
+		}
+
+		photoURL := existingTodo.PhotoURL
+		holdsPhoto := false
+		if photo != nil {
+			// Сначала ссылаемся на новое фото, потом отпускаем старое:
+			// если это тот же файл, счётчик не успеет упасть до нуля
+			if photoURL, err = h.photos().acquire(ctx, tx, photo); err != nil {
+				return err
+			}
+			holdsPhoto = h.undoable(actorID) && existingTodo.PhotoURL != nil
+			if !holdsPhoto {
+				if err := h.photos().release(ctx, tx, existingTodo.PhotoURL); err != nil {
+					return err
+				}
+			}
+		}
+
+		updated := models.Todo{
+			Title:    in.Title,
+			Done:     in.Done,
+			PhotoURL: photoURL,
+			UserID:   existingTodo.UserID,
+		}
+		todo, err = tx.UpdateTodo(ctx, id, updated)
+		if err != nil {
+			return err
+		}
+		entry := audit.Entry(ctx, actorID, audit.TodoUpdate, audit.TargetTodo, id)
+		if err := recordAudit(ctx, tx, entry, existingTodo, todo); err != nil {
+			return err
+		}
+		before := models.SnapshotOf(existingTodo)
+		undoToken, err = h.journal(ctx, tx, models.TodoOperation{
+			UserID:     actorID,
+			TodoID:     id,
+			Op:         models.OpUpdate,
+			Before:     &before,
+			After:      models.SnapshotOf(todo),
+			HoldsPhoto: holdsPhoto,
+		})
+		return err
+	})
+	if err != nil {
+		return models.Todo{}, "", err
+	}
+	return todo, undoToken, nil
+}
+
+// remove переносит задачу в корзину. Фото остаётся за задачей до
+// окончательного удаления, чтобы её можно было восстановить.
+func (h *TodoHandler) remove(ctx context.Context, actorID, id int) (string, error) {
+	var undoToken string
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		existingTodo, err := lockOwnTodo(ctx, tx, actorID, id, false)
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteTodo(ctx, id); err != nil {
+			return err
+		}
+		entry := audit.Entry(ctx, actorID, audit.TodoDelete, audit.TargetTodo, id)
+		if err := recordAudit(ctx, tx, entry, existingTodo, nil); err != nil {
+			return err
+		}
+		undoToken, err = h.journal(ctx, tx, models.TodoOperation{
+			UserID: actorID,
+			TodoID: id,
+			Op:     models.OpDelete,
+			After:  models.SnapshotOf(existingTodo),
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return undoToken, nil
+}
+
+// restore возвращает задачу из корзины
+func (h *TodoHandler) restore(ctx context.Context, actorID, id int) (models.Todo, string, error) {
+	var (
+		restored  models.Todo
+		undoToken string
+	)
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		deleted, err := lockOwnTodo(ctx, tx, actorID, id, true)
+		if err != nil {
+			return err
+		}
+		if restored, err = tx.RestoreTodo(ctx, id); err != nil {
+			return err
+		}
+		entry := audit.Entry(ctx, actorID, audit.TodoRestore, audit.TargetTodo, id)
+		if err := recordAudit(ctx, tx, entry, deleted, restored); err != nil {
+			return err
+		}
+		undoToken, err = h.journal(ctx, tx, models.TodoOperation{
+			UserID: actorID,
+			TodoID: id,
+			Op:     models.OpRestore,
+			After:  models.SnapshotOf(restored),
+		})
+		return err
+	})
+	if err != nil {
+		return models.Todo{}, "", err
+	}
+	return restored, undoToken, nil
+}
+
+// purge окончательно удаляет задачи из корзины с ID, которые вернёт load, и
+// отпускает их фото. Каждая задача блокируется и проверяется заново:
+// восстановленная параллельно или чужая задача пропускается, остальные
+// удаляются. Возвращает число удалённых задач.
+// Только purge допускает systemActor — владелец тогда не проверяется.
+func (h *TodoHandler) purge(ctx context.Context, actorID int, load func(store.Tx) ([]int, error)) (int, error) {
+	var purged int
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		ids, err := load(tx)
+		if err != nil {
+			return err
+		}
+
+		purged = 0
+		for _, id := range ids {
+			var todo models.Todo
+			if actorID == systemActor {
+				todo, err = lockTodo(ctx, tx, id, true)
+			} else {
+				todo, err = lockOwnTodo(ctx, tx, actorID, id, true)
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.PurgeTodo(ctx, todo.ID); err != nil {
+				return err
+			}
+			if err := h.photos().release(ctx, tx, todo.PhotoURL); err != nil {
+				return err
+			}
+
+			entry := audit.Entry(ctx, actorID, audit.TodoPurge, audit.TargetTodo, todo.ID)
+			if err := recordAudit(ctx, tx, entry, todo, nil); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// todoIDs — ID задач из результата выборки, для purge
+func todoIDs(todos []models.Todo, err error) ([]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"todo-api/auth"
+	"todo-api/validation"
+)
+
+// searchQueryMaxLength — ограничение длины поискового запроса в символах
+const searchQueryMaxLength = 200
+
+// @Summary      Search todos
+// @Description  Полнотекстовый поиск по названиям задач пользователя. Слова ищутся с учётом морфологии (русский и английский) и по префиксу, должны найтись все слова запроса. Самые релевантные задачи первыми; highlight — название с найденными словами в <mark>, остальной текст экранирован.
+// @Tags         todos
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q       query     string  true   "Поисковый запрос"
+// @Param        limit   query     int     false  "Сколько задач вернуть (1–100)"  default(20)
+// @Param        offset  query     int     false  "Сколько задач пропустить"  default(0)
+// @Success      200  {object}  models.GeneralResponse{data=[]models.TodoSearchResult}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /todos/search [get]
+func (h *TodoHandler) searchTodos(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	v := validation.New()
+	v.Required("q", q)
+	if v.Valid() {
+		v.Length("q", q, 1, searchQueryMaxLength)
+	}
+	if err := v.Err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	p, err := parsePage(r, 20, 100)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	results, err := h.Store.SearchTodos(r.Context(), userID, q, p.Limit, p.Offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Todos found", results, http.StatusOK)
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-api/events"
+	"todo-api/logging"
+	"todo-api/models"
+	"todo-api/ratelimit"
+	"todo-api/store"
+	"todo-api/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Протокол WebSocket: JSON-сообщения с полем type.
+//
+// Клиент → сервер: subscribe (с необязательным last_event_id), create, update, delete.
+// Сервер → клиент: ack (успех запроса с тем же id), error (ошибка в формате
+// ProblemDetails), event (изменение задачи), reset (пропущенные события
+// не восстановить — перечитайте список).
+const (
+	msgSubscribe = "subscribe"
+	msgCreate    = "create"
+	msgUpdate    = "update"
+	msgDelete    = "delete"
+	msgAck       = "ack"
+	msgError     = "error"
+	msgEvent     = "event"
+	msgReset     = "reset"
+)
+
+// socketWriteTimeout — сколько ждать записи одного сообщения клиенту
+const socketWriteTimeout = 10 * time.Second
+
+type socketRequest struct {
+	Type string `json:"type"`
+	// ID — идентификатор запроса на стороне клиента, возвращается в ack/error
+	ID          string       `json:"id,omitempty"`
+	TodoID      int          `json:"todo_id,omitempty"`
+	Todo        *models.Todo `json:"todo,omitempty"`
+	LastEventID string       `json:"last_event_id,omitempty"`
+}
+
+type socketMessage struct {
+	Type  string                 `json:"type"`
+	ID    string                 `json:"id,omitempty"`
+	Todo  *models.Todo           `json:"todo,omitempty"`
+	Event *events.Event          `json:"event,omitempty"`
+	Error *models.ProblemDetails `json:"error,omitempty"`
+	// UndoToken — в ack на изменение, для POST /api/undo
+	UndoToken string `json:"undo_token,omitempty"`
+}
+
+// SocketOptions — ограничения одного WebSocket-соединения
+type SocketOptions struct {
+	// AllowedOrigins — кроме своего origin'а; "*" разрешает любой
+	AllowedOrigins  []string
+	MessageRate     ratelimit.Rate
+	MaxMessageBytes int64
+	SendBuffer      int
+	PingInterval    time.Duration
+}
+
+// TodoSocket — WebSocket-API задач. Изменения идут через те же проверки
+// и методы TodoHandler, что и REST, поэтому рассылаются всем клиентам.
+type TodoSocket struct {
+	Todos *TodoHandler
+	Hub   *events.Hub
+	Opts  SocketOptions
+
+	upgrader websocket.Upgrader
+
+	mu    sync.Mutex
+	conns map[*socketConn]struct{}
+}
+
+func NewTodoSocket(todos *TodoHandler, hub *events.Hub, opts SocketOptions) *TodoSocket {
+	s := &TodoSocket{Todos: todos, Hub: hub, Opts: opts, conns: map[*socketConn]struct{}{}}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	return s
+}
+
+func (s *TodoSocket) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/todos/ws", s.serve).Methods(http.MethodGet)
+}
+
+// checkOrigin пускает браузеры только со своего origin'а или из списка разрешённых.
+// Клиенты без заголовка Origin (не браузеры) проходят.
+func (s *TodoSocket) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.Opts.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown закрывает все соединения: сервер не отслеживает захваченные
+// (hijacked) соединения и сам их не закроет
+func (s *TodoSocket) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+// socketConn — одно соединение. Писать в websocket.Conn может только
+// writer; остальные кладут сообщения в очередь send.
+type socketConn struct {
+	ws     *websocket.Conn
+	userID int
+	send   chan socketMessage
+	done   chan struct{}
+	once   sync.Once
+
+	mu  sync.Mutex
+	sub *events.Subscription
+}
+
+// @Summary      Todo WebSocket
+// @Description  Двусторонняя синхронизация задач. Клиент шлёт subscribe, create, update, delete
+// @Description  (JSON с полями type, id, todo_id, todo, last_event_id); сервер отвечает ack или error
+// @Description  с тем же id и присылает event/reset после подписки. Токен — заголовок Authorization
+// @Description  или параметр access_token.
+// @Tags         todos
+// @Security     BearerAuth
+// @Param        access_token  query     string  false  "JWT, если нельзя передать заголовок Authorization"
+// @Success      101           {string}  string  "Switching Protocols"
+// @Failure      401           {object}  models.GeneralResponse
+// @Router       /todos/ws [get]
+func (s *TodoSocket) serve(w http.ResponseWriter, r *http.Request) {
+	userID, err := streamUserID(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader уже ответил клиенту
+		logging.FromContext(r.Context()).Debug("websocket upgrade failed", "error", err)
+		return
+	}
+
+	c := &socketConn{
+		ws:     ws,
+		userID: userID,
+		send:   make(chan socketMessage, max(s.Opts.SendBuffer, 1)),
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.unsubscribe()
+		c.close(websocket.CloseNormalClosure, "")
+	}()
+
+	go c.writeLoop(s.pingInterval())
+	s.readLoop(r.Context(), c)
+}
+
+func (s *TodoSocket) pingInterval() time.Duration {
+	if s.Opts.PingInterval > 0 {
+		return s.Opts.PingInterval
+	}
+	return defaultHeartbeat
+}
+
+// readLoop обрабатывает сообщения клиента по одному: пока запрос
+// выполняется, следующий не читается
+func (s *TodoSocket) readLoop(ctx context.Context, c *socketConn) {
+	logger := logging.FromContext(ctx)
+	limiter := ratelimit.NewMemoryLimiter(s.Opts.MessageRate)
+	pongWait := 2 * s.pingInterval()
+
+	if s.Opts.MaxMessageBytes > 0 {
+		c.ws.SetReadLimit(s.Opts.MaxMessageBytes)
+	}
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			logger.Debug("websocket closed", "error", err)
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		// Лимит считает все сообщения, в том числе битые
+		if s.Opts.MessageRate.Limit > 0 {
+			if res, _ := limiter.Allow(ctx, "conn"); !res.Allowed {
+				p := newProblem(http.StatusTooManyRequests, "Too many messages")
+				c.reply(socketMessage{Type: msgError, Error: &p})
+				continue
+			}
+		}
+
+		var req socketRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.reply(socketMessage{Type: msgError, Error: problemPtr(badRequest("Invalid JSON"))})
+			continue
+		}
+
+		msg, err := s.handle(ctx, c, req)
+		if err != nil {
+			p := problemFromError(err)
+			if p.Status >= http.StatusInternalServerError {
+				logger.Error("websocket request failed", "type", req.Type, "error", err)
+			}
+			msg = socketMessage{Type: msgError, Error: &p}
+		}
+		msg.ID = req.ID
+		c.reply(msg)
+	}
+}
+
+func (s *TodoSocket) handle(ctx context.Context, c *socketConn, req socketRequest) (socketMessage, error) {
+	switch req.Type {
+	case msgSubscribe:
+		if s.Hub == nil {
+			return socketMessage{}, &httpError{Status: http.StatusServiceUnavailable, Message: "Event stream is disabled"}
+		}
+		c.subscribe(s.Hub, req.LastEventID)
+		return socketMessage{Type: msgAck}, nil
+
+	case msgCreate:
+		if req.Todo == nil {
+			return socketMessage{}, store.NewValidationError("todo", "required", "todo is required")
+		}
+		in := models.Todo{Title: req.Todo.Title, Done: req.Todo.Done, UserID: c.userID}
+		if err := validation.Todo(in); err != nil {
+			return socketMessage{}, err
+		}
+		created, undoToken, err := s.Todos.create(ctx, in, nil)
+		if err != nil {
+			return socketMessage{}, err
+		}
+		return socketMessage{Type: msgAck, Todo: &created, UndoToken: undoToken}, nil
+
+	case msgUpdate:
+		if req.Todo == nil {
+			return socketMessage{}, store.NewValidationError("todo", "required", "todo is required")
+		}
+		in := models.Todo{Title: req.Todo.Title, Done: req.Todo.Done}
+		if err := validation.Todo(in); err != nil {
+			return socketMessage{}, err
+		}
+		updated, undoToken, err := s.Todos.update(ctx, c.userID, req.TodoID, in, nil)
+		if err != nil {
+			return socketMessage{}, err
+		}
+		return socketMessage{Type: msgAck, Todo: &updated, UndoToken: undoToken}, nil
+
+	case msgDelete:
+		undoToken, err := s.Todos.remove(ctx, c.userID, req.TodoID)
+		if err != nil {
+			return socketMessage{}, err
+		}
+		return socketMessage{Type: msgAck, UndoToken: undoToken}, nil
+
+	default:
+		return socketMessage{}, store.NewValidationError("type", "unknown_type", "unknown message type "+req.Type)
+	}
+}
+
+// reply ставит сообщение в очередь. Если клиент не успевает читать
+// и очередь полна, соединение закрывается — это и есть backpressure.
+func (c *socketConn) reply(msg socketMessage) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close(websocket.ClosePolicyViolation, "client is too slow")
+	}
+}
+
+// subscribe (пере)подписывает соединение на события пользователя
+func (c *socketConn) subscribe(hub *events.Hub, lastEventID string) {
+	c.unsubscribe()
+
+	sub, missed, resumed := hub.Subscribe(c.userID, lastEventID)
+	c.mu.Lock()
+	c.sub = sub
+	c.mu.Unlock()
+
+	if !resumed {
+		c.reply(socketMessage{Type: msgReset})
+	}
+	for i := range missed {
+		c.reply(socketMessage{Type: msgEvent, Event: &missed[i]})
+	}
+
+	go func() {
+		for ev := range sub.C {
+			c.reply(socketMessage{Type: msgEvent, Event: &ev})
+		}
+		// Хаб закрыл подписку сам (остановка сервера) — закрываем и соединение
+		select {
+		case <-c.done:
+		default:
+			c.mu.Lock()
+			current := c.sub == sub
+			c.mu.Unlock()
+			if current {
+				c.close(websocket.CloseGoingAway, "event stream closed")
+			}
+		}
+	}()
+}
+
+func (c *socketConn) unsubscribe() {
+	c.mu.Lock()
+	sub := c.sub
+	c.sub = nil
+	c.mu.Unlock()
+	if sub != nil {
+		sub.Close()
+	}
+}
+
+// writeLoop — единственный писатель в соединение: сообщения из очереди и пинги
+func (c *socketConn) writeLoop(pingInterval time.Duration) {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			if err := c.ws.WriteJSON(msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// close отправляет клиенту кадр закрытия (если код это допускает) и рвёт соединение
+func (c *socketConn) close(code int, reason string) {
+	c.once.Do(func() {
+		close(c.done)
+		if code != websocket.CloseAbnormalClosure {
+			c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		}
+		c.ws.Close()
+	})
+}
+
+func problemPtr(err error) *models.ProblemDetails {
+	p := problemFromError(err)
+	return &p
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/events"
+)
+
+// TodoStream — настройки потока изменений задач
+type TodoStream struct {
+	Hub *events.Hub
+	// Heartbeat — период комментариев-пингов, не дающих прокси закрыть простаивающий поток
+	Heartbeat time.Duration
+}
+
+const (
+	// streamRetry — через сколько EventSource переподключится после обрыва
+	streamRetry      = 3 * time.Second
+	defaultHeartbeat = 15 * time.Second
+)
+
+// streamUserID достаёт пользователя из JWT. EventSource в браузере не умеет
+// передавать заголовки, поэтому токен можно передать и параметром access_token.
+func streamUserID(r *http.Request) (int, error) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if errors.Is(err, auth.ErrNoAuthHeader) {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			claims, err := auth.ParseJWTToken(token)
+			if err != nil {
+				return 0, err
+			}
+			return claims.UserID, nil
+		}
+	}
+	return userID, err
+}
+
+// @Summary      Stream todo changes
+// @Description  Server-Sent Events со всеми изменениями задач пользователя: todo.created, todo.updated, todo.completed,
+// @Description  todo.deleted (перенос в корзину), todo.restored, а также user.deleted.
+// @Description  Доставка не менее одного раза: повтор события приходит с тем же seq.
+// @Description  После обрыва клиент присылает Last-Event-ID и получает пропущенные события; если продолжить нельзя,
+// @Description  приходит событие reset — список нужно перечитать через GET /todos.
+// @Description  Токен можно передать параметром access_token (EventSource не умеет заголовки).
+// @Tags         todos
+// @Produce      text/event-stream
+// @Security     BearerAuth
+// @Param        Last-Event-ID  header    string  false  "ID последнего полученного события"
+// @Param        access_token   query     string  false  "JWT, если нельзя передать заголовок Authorization"
+// @Success      200            {string}  string  "event stream"
+// @Failure      401            {object}  models.GeneralResponse
+// @Failure      503            {object}  models.GeneralResponse
+// @Router       /todos/stream [get]
+func (h *TodoHandler) streamTodos(w http.ResponseWriter, r *http.Request) {
+	userID, err := streamUserID(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	if h.Stream.Hub == nil {
+		writeError(w, r, &httpError{Status: http.StatusServiceUnavailable, Message: "Event stream is disabled"})
+		return
+	}
+
+	// Поток живёт дольше таймаутов сервера: снимаем дедлайны для этого соединения
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, r, fmt.Errorf("disable write deadline: %w", err))
+		return
+	}
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, r, fmt.Errorf("disable read deadline: %w", err))
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	sub, missed, resumed := h.Stream.Hub.Subscribe(userID, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range missed {
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	interval := h.Stream.Heartbeat
+	if interval <= 0 {
+		interval = defaultHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// Отстали или сервер останавливается: клиент переподключится с Last-Event-ID
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent пишет событие в формате text/event-stream
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
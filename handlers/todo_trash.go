@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/store"
+
+	"github.com/gorilla/mux"
+)
+
+// trashID разбирает ID задачи из пути
+func trashID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, invalidID()
+	}
+	return id, nil
+}
+
+// @Summary      List deleted todos
+// @Description  Корзина: удалённые задачи пользователя, недавно удалённые первыми. Через срок хранения задачи удаляются окончательно.
+// @Tags         trash
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.GeneralResponse{data=[]models.Todo}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /todos/trash [get]
+func (h *TodoHandler) getTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	todos, err := h.Store.GetTrash(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Trash fetched", todos, http.StatusOK)
+}
+
+// @Summary      Restore a deleted todo
+// @Description  Вернуть задачу из корзины
+// @Tags         trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Todo ID"
+// @Success      200  {object}  models.GeneralResponse{data=models.Todo}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /todos/{id}/restore [post]
+func (h *TodoHandler) restoreTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	id, err := trashID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo, undoToken, err := h.restore(r.Context(), userID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeUndoableResponse(w, "Todo restored", todo, undoToken, http.StatusOK)
+}
+
+// @Summary      Permanently delete a todo
+// @Description  Окончательно удалить задачу из корзины вместе с её фото
+// @Tags         trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Todo ID"
+// @Success      200  {object}  models.GeneralResponse
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /todos/trash/{id} [delete]
+func (h *TodoHandler) purgeTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+	id, err := trashID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	purged, err := h.purge(r.Context(), userID, func(store.Tx) ([]int, error) {
+		return []int{id}, nil
+	})
+	if err == nil && purged == 0 {
+		err = &store.NotFoundError{Entity: "todo", ID: id}
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Todo purged", nil, http.StatusOK)
+}
+
+// @Summary      Empty the trash
+// @Description  Окончательно удалить все задачи из корзины пользователя
+// @Tags         trash
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.GeneralResponse{data=object{purged=int}}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /todos/trash [delete]
+func (h *TodoHandler) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	purged, err := h.purge(r.Context(), userID, func(tx store.Tx) ([]int, error) {
+		return todoIDs(tx.GetTrash(r.Context(), userID))
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Trash emptied", map[string]int{"purged": purged}, http.StatusOK)
+}
+
+// PurgeExpiredTrash окончательно удаляет задачи, пролежавшие в корзине
+// дольше retention, пачками по batchSize. Возвращает их число. В журнале
+// аудита такие удаления записываются без действующего.
+func (h *TodoHandler) PurgeExpiredTrash(ctx context.Context, retention time.Duration, batchSize int) (int, error) {
+	total := 0
+	for {
+		n, err := h.purge(ctx, systemActor, func(tx store.Tx) ([]int, error) {
+			return todoIDs(tx.GetExpiredTrash(ctx, retention, batchSize))
+		})
+		total += n
+		// Неполная пачка — корзина разобрана, либо часть задач восстановили
+		// параллельно; остаток подберёт следующий проход
+		if err != nil || n < batchSize {
+			return total, err
+		}
+	}
+}
+
+// RunTrashRetention чистит корзину каждые interval до отмены ctx
+func (h *TodoHandler) RunTrashRetention(ctx context.Context, interval, retention time.Duration, batchSize int) {
+	slog.Info("trash retention started", "interval", interval, "retention", retention)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := h.PurgeExpiredTrash(ctx, retention, batchSize)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.Error("trash retention failed", "purged", n, "error", err)
+		case n > 0:
+			slog.Info("expired todos purged", "purged", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+
+	"github.com/gorilla/mux"
+)
+
+// trashTx — хранилище задач в памяти для purge. Остальные методы store.Tx
+// не реализованы: их вызов — ошибка в тесте.
+type trashTx struct {
+	store.Tx
+	todos  map[int]models.Todo
+	purged []int
+}
+
+func (tx *trashTx) WithTx(_ context.Context, fn func(store.Tx) error) error { return fn(tx) }
+
+func (tx *trashTx) GetTrash(_ context.Context, userID int) ([]models.Todo, error) {
+	var trash []models.Todo
+	for _, id := range []int{1, 2, 3, 4} {
+		if todo, ok := tx.todos[id]; ok && todo.UserID == userID {
+			trash = append(trash, todo)
+		}
+	}
+	return trash, nil
+}
+
+func (tx *trashTx) LockTodo(_ context.Context, id int) (models.Todo, error) {
+	todo, ok := tx.todos[id]
+	if !ok {
+		return models.Todo{}, &store.NotFoundError{Entity: "todo", ID: id}
+	}
+	return todo, nil
+}
+
+func (tx *trashTx) PurgeTodo(_ context.Context, id int) error {
+	delete(tx.todos, id)
+	tx.purged = append(tx.purged, id)
+	return nil
+}
+
+func (tx *trashTx) RecordAudit(context.Context, models.AuditEntry) error { return nil }
+
+func newTrashTx() *trashTx {
+	deleted := time.Now()
+	return &trashTx{todos: map[int]models.Todo{
+		1: {ID: 1, Title: "a", UserID: 7, DeletedAt: &deleted},
+		2: {ID: 2, Title: "b", UserID: 7, DeletedAt: &deleted},
+		3: {ID: 3, Title: "c", UserID: 7, DeletedAt: &deleted},
+		4: {ID: 4, Title: "d", UserID: 8, DeletedAt: &deleted},
+	}}
+}
+
+func authorized(t *testing.T, r *http.Request, userID int) *http.Request {
+	t.Helper()
+	token, err := auth.CreateJWTToken(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestEmptyTrashSkipsRestoredTodos(t *testing.T) {
+	tx := newTrashTx()
+	h := NewTodoHandler(nil, tx, nil, TodoStream{}, 0)
+
+	// Задачу 2 восстановили между выборкой корзины и её блокировкой
+	load := func(s store.Tx) ([]int, error) {
+		ids, err := todoIDs(s.GetTrash(context.Background(), 7))
+		restored := tx.todos[2]
+		restored.DeletedAt = nil
+		tx.todos[2] = restored
+		return ids, err
+	}
+	purged, err := h.purge(context.Background(), 7, load)
+	if err != nil {
+		t.Fatalf("purge() error = %v", err)
+	}
+	if purged != 2 || len(tx.purged) != 2 || tx.purged[0] != 1 || tx.purged[1] != 3 {
+		t.Errorf("purged %d todos %v, want 2 todos [1 3]", purged, tx.purged)
+	}
+	if _, ok := tx.todos[2]; !ok {
+		t.Error("restored todo was purged")
+	}
+}
+
+func TestPurgeTodo(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     int
+		status int
+	}{
+		{"own todo", 1, http.StatusOK},
+		{"foreign todo", 4, http.StatusNotFound},
+		{"missing todo", 9, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newTrashTx()
+			h := NewTodoHandler(nil, tx, nil, TodoStream{}, 0)
+
+			id := strconv.Itoa(tt.id)
+			req := authorized(t, httptest.NewRequest(http.MethodDelete, "/api/todos/trash/"+id, nil), 7)
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+			rec := httptest.NewRecorder()
+			h.purgeTodo(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var resp models.GeneralResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("response is not a JSON envelope: %v", err)
+			}
+			if wantPurged := tt.status == http.StatusOK; (len(tx.purged) == 1) != wantPurged {
+				t.Errorf("purged = %v", tx.purged)
+			}
+		})
+	}
+}
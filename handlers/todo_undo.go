@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"todo-api/audit"
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// Отмена операций над задачами. Каждое изменение пишет в журнал снимки
+// задачи до и после и отдаёт клиенту undo_token. Отмена возвращает снимок
+// «до», только если задача с тех пор не менялась. Старое фото при замене
+// не освобождается, а переходит к записи журнала до конца окна отмены.
+
+// errTodoChanged — задача изменилась после операции, отменять её нельзя
+var errTodoChanged = &httpError{Status: http.StatusConflict, Message: "Todo has changed since the operation"}
+
+// undoRequest — тело POST /undo
+type undoRequest struct {
+	UndoToken string `json:"undo_token"`
+}
+
+// newUndoToken генерирует токен отмены
+func newUndoToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "undo_" + hex.EncodeToString(b), nil
+}
+
+// undoable сообщает, попадёт ли операция userID в журнал отмены: отмена
+// должна быть включена, а выполнивший операцию — известен
+func (h *TodoHandler) undoable(userID int) bool {
+	return h.UndoWindow > 0 && userID != 0
+}
+
+// journal записывает операцию в журнал отмены и возвращает её токен.
+// Пустой токен — операция не отменяемая (см. undoable).
+func (h *TodoHandler) journal(ctx context.Context, tx store.Tx, op models.TodoOperation) (string, error) {
+	if !h.undoable(op.UserID) {
+		return "", nil
+	}
+	token, err := newUndoToken()
+	if err != nil {
+		return "", err
+	}
+	op.Token = token
+	op.ExpiresAt = time.Now().Add(h.UndoWindow)
+	if _, err := tx.RecordTodoOperation(ctx, op); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// releaseOperationPhotos отпускает фото, которые держат удалённые записи
+// журнала
+func releaseOperationPhotos(ctx context.Context, photos photoRefs, tx store.Tx, ops []models.TodoOperation) error {
+	for _, op := range ops {
+		if !op.HoldsPhoto || op.Before == nil {
+			continue
+		}
+		if err := photos.release(ctx, tx, op.Before.PhotoURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undo отменяет операцию по токену от имени её автора userID
+func (h *TodoHandler) undo(ctx context.Context, userID int, token string) (models.Todo, error) {
+	var todo models.Todo
+	err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+		op, err := tx.GetTodoOperation(ctx, token)
+		if err != nil {
+			return err
+		}
+		// Чужой токен выглядит как несуществующий
+		if op.UserID != userID {
+			return &store.NotFoundError{Entity: "operation"}
+		}
+		if op.UndoneAt != nil {
+			return &httpError{Status: http.StatusConflict, Message: "Operation has already been undone"}
+		}
+		if !time.Now().Before(op.ExpiresAt) {
+			return &httpError{Status: http.StatusGone, Message: "Undo window has expired"}
+		}
+
+		current, err := lockOwnTodo(ctx, tx, userID, op.TodoID, op.Op == models.OpDelete)
+		if errors.Is(err, store.ErrNotFound) {
+			return errTodoChanged
+		}
+		if err != nil {
+			return err
+		}
+		if !op.After.Matches(current) {
+			return errTodoChanged
+		}
+
+		switch op.Op {
+		case models.OpCreate, models.OpRestore:
+			if err := tx.DeleteTodo(ctx, op.TodoID); err != nil {
+				return err
+			}
+			if todo, err = tx.GetDeletedTodo(ctx, op.TodoID); err != nil {
+				return err
+			}
+		case models.OpDelete:
+			if todo, err = tx.RestoreTodo(ctx, op.TodoID); err != nil {
+				return err
+			}
+		case models.OpUpdate:
+			previous := models.Todo{
+				Title:    op.Before.Title,
+				Done:     op.Before.Done,
+				PhotoURL: op.Before.PhotoURL,
+				UserID:   current.UserID,
+			}
+			if todo, err = tx.UpdateTodo(ctx, op.TodoID, previous); err != nil {
+				return err
+			}
+			// Ссылку на старое фото держала запись журнала — теперь она снова
+			// у задачи, а отпустить нужно фото, поставленное операцией
+			if op.PhotoChanged() {
+				if err := h.photos().release(ctx, tx, current.PhotoURL); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.MarkTodoOperationUndone(ctx, op.ID); err != nil {
+			return err
+		}
+		entry := audit.Entry(ctx, userID, audit.TodoUndo, audit.TargetTodo, op.TodoID)
+		return recordAudit(ctx, tx, entry, current, todo)
+	})
+	if err != nil {
+		return models.Todo{}, err
+	}
+	return todo, nil
+}
+
+// @Summary      Undo a recent todo operation
+// @Description  Отменить создание, изменение, удаление или восстановление задачи по undo_token из ответа на изменение. Отмена возможна в течение окна отмены и только если задача с тех пор не менялась (иначе 409).
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body      object{undo_token=string}  true  "Токен отмены"
+// @Success      200   {object}  models.GeneralResponse{data=models.Todo}
+// @Failure      400   {object}  models.GeneralResponse
+// @Failure      401   {object}  models.GeneralResponse
+// @Failure      404   {object}  models.GeneralResponse
+// @Failure      409   {object}  models.GeneralResponse
+// @Failure      410   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
+// @Router       /undo [post]
+func (h *TodoHandler) undoOperation(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	var req undoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if req.UndoToken == "" {
+		writeError(w, r, store.NewValidationError("undo_token", "required", "undo_token is required"))
+		return
+	}
+
+	todo, err := h.undo(r.Context(), userID, req.UndoToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Operation undone", todo, http.StatusOK)
+}
+
+// ExpireUndo удаляет записи журнала с истёкшим окном отмены пачками по
+// batchSize и отпускает удерживаемые ими фото. Возвращает число записей.
+func (h *TodoHandler) ExpireUndo(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for {
+		var n int
+		err := h.Tx.WithTx(ctx, func(tx store.Tx) error {
+			ops, err := tx.TakeExpiredTodoOperations(ctx, batchSize)
+			if err != nil {
+				return err
+			}
+			n = len(ops)
+			return releaseOperationPhotos(ctx, h.photos(), tx, ops)
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunUndoExpiry чистит журнал отмены каждые interval до отмены ctx
+func (h *TodoHandler) RunUndoExpiry(ctx context.Context, interval time.Duration, batchSize int) {
+	slog.Info("undo expiry started", "interval", interval, "window", h.UndoWindow)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := h.ExpireUndo(ctx, batchSize)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.Error("undo expiry failed", "expired", n, "error", err)
+		case n > 0:
+			slog.Debug("undo operations expired", "expired", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,23 +1,38 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	"todo-api/audit"
 	"todo-api/auth"
+	"todo-api/events"
+	"todo-api/logging"
+	"todo-api/metrics"
 	"todo-api/models"
 	"todo-api/store"
+	"todo-api/uploads"
+	"todo-api/validation"
+	"todo-api/webhooks"
 
 	"github.com/gorilla/mux"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserHandler struct {
-	Store store.UserStore
+	Store   store.UserStore
+	Tx      store.Transactor
+	Uploads uploads.Storage
+	Guard   LoginProtection
+	// Audit получает записи о входах; изменения пишутся в журнал в их транзакциях
+	Audit store.AuditStore
 }
 
-func NewUserHandler(store store.UserStore) *UserHandler {
-	return &UserHandler{Store: store}
+func NewUserHandler(store store.UserStore, tx store.Transactor, files uploads.Storage, guard LoginProtection, audit store.AuditStore) *UserHandler {
+	return &UserHandler{Store: store, Tx: tx, Uploads: files, Guard: guard, Audit: audit}
 }
 
 func (h *UserHandler) RegisterRoutes(r *mux.Router) {
@@ -27,6 +42,7 @@ func (h *UserHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/users", h.GetAllUsers).Methods("GET")
 	r.HandleFunc("/api/users/{id}", h.GetUserByID).Methods("GET")
 	r.HandleFunc("/api/users/{id}", h.UpdateUser).Methods("PUT")
+	r.HandleFunc("/api/users/{id}/password", h.ChangePassword).Methods("POST")
 	r.HandleFunc("/api/users/{id}", h.DeleteUser).Methods("DELETE")
 }
 
@@ -35,6 +51,22 @@ type userCredentials struct {
 	Password string `json:"password" example:"password123"`
 }
 
+// Validate проверяет данные для регистрации
+func (c userCredentials) Validate() error {
+	v := validation.New()
+	validation.Username(v, "username", c.Username)
+	validation.Password(v, "password", c.Password)
+	return v.Err()
+}
+
+// validateLogin проверяет только наличие полей: правила надёжности при входе не раскрываем
+func (c userCredentials) validateLogin() error {
+	v := validation.New()
+	v.Required("username", c.Username)
+	v.Required("password", c.Password)
+	return v.Err()
+}
+
 // Register godoc
 // @Summary      Register a new user
 // @Description  Register a new user with username and password
@@ -44,29 +76,48 @@ type userCredentials struct {
 // @Param        user  body      userCredentials  true  "User registration info"
 // @Success      201   {object}  models.GeneralResponse{data=models.User}
 // @Failure      400   {object}  models.GeneralResponse
+// @Failure      409   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
+// @Failure      429   {object}  models.GeneralResponse
 // @Failure      500   {object}  models.GeneralResponse
 // @Router       /register [post]
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if !h.Guard.allowRegister(w, r) {
+		return
+	}
+
 	var input userCredentials
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeGeneralResponse(w, "error", "Invalid JSON", nil, http.StatusBadRequest)
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(r.Context(), input.Password)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Error hashing password", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("hash password: %w", err))
 		return
 	}
 
 	user := models.User{
 		Username:     input.Username,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 	}
 
-	createdUser, err := h.Store.CreateUser(user)
+	var createdUser models.User
+	err = h.Tx.WithTx(r.Context(), func(tx store.Tx) error {
+		createdUser, err = tx.CreateUser(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		entry := audit.Entry(r.Context(), createdUser.ID, audit.Register, audit.TargetUser, createdUser.ID)
+		return recordAudit(r.Context(), tx, entry, nil, createdUser)
+	})
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to create user", nil, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -76,7 +127,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate user and return JWT token
+// @Description  Authenticate user and return JWT token. Any failure (unknown user, wrong password, locked account) yields the same 401
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -84,38 +135,77 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Success      200   {object}  models.GeneralResponse{data=object{token=string}}
 // @Failure      400   {object}  models.GeneralResponse
 // @Failure      401   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
+// @Failure      429   {object}  models.GeneralResponse
 // @Router       /login [post]
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var creds userCredentials
 	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		writeGeneralResponse(w, "error", "Invalid JSON", nil, http.StatusBadRequest)
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if err := creds.validateLogin(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.Store.GetByUsername(creds.Username)
-	if err != nil {
-		writeGeneralResponse(w, "error", "User not found", nil, http.StatusUnauthorized)
+	if !h.Guard.allowLogin(w, r, creds.Username) {
+		metrics.LoginAttempts.WithLabelValues("throttled").Inc()
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
-		writeGeneralResponse(w, "error", "Invalid password", nil, http.StatusUnauthorized)
+	user, err := h.Store.GetByUsername(r.Context(), creds.Username)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		writeError(w, r, err)
 		return
 	}
+	// Несуществующий пользователь и заблокированный аккаунт проходят ту же
+	// проверку bcrypt, что и обычный вход, — по времени ответа их не отличить
+	if err != nil || user.Locked(time.Now()) {
+		checkDummyPassword(r.Context(), creds.Password)
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		h.auditLoginFailure(r.Context(), user.ID)
+		writeError(w, r, errInvalidCredentials)
+		return
+	}
+
+	if err := checkPassword(r.Context(), user.PasswordHash, creds.Password); err != nil {
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		h.auditLoginFailure(r.Context(), user.ID)
+		if err := h.Guard.loginFailed(r.Context(), h.Store, user); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeError(w, r, errInvalidCredentials)
+		return
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := h.Store.ResetLoginFailures(r.Context(), user.ID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
 
 	token, err := auth.CreateJWTToken(user.ID)
 	refreshToken, err1 := auth.CreateRefreshToken(user.ID)
 	if err != nil || err1 != nil {
-		writeGeneralResponse(w, "error", "Failed to create refresh token", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("create tokens: %w", errors.Join(err, err1)))
+		return
+	}
 
+	// Без записи в журнале токены не выдаём
+	entry := audit.Entry(r.Context(), user.ID, audit.Login, audit.TargetUser, user.ID)
+	if err := recordAudit(r.Context(), h.Audit, entry, nil, nil); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	writeGeneralResponse(w, "success", "Login successful", map[string]string{
 		"access_token":  token,
 		"refresh_token": refreshToken,
 	}, http.StatusOK)
-
 }
 
 // GetAllUsers godoc
@@ -127,9 +217,9 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}  models.GeneralResponse
 // @Router       /users [get]
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Store.GetUsers()
+	users, err := h.Store.GetUsers(r.Context())
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to fetch users", nil, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	for i := range users {
@@ -145,20 +235,20 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        id   path      int  true  "User ID"
 // @Success      200  {object}  models.GeneralResponse{data=models.User}
-// @Failure      400  {object}  models.GeneralResponse
 // @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
 // @Router       /users/{id} [get]
 func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := mux.Vars(r)["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Invalid ID", nil, http.StatusBadRequest)
+		writeError(w, r, invalidID())
 		return
 	}
 
-	user, err := h.Store.GetUserByID(id)
+	user, err := h.Store.GetUserByID(r.Context(), id)
 	if err != nil {
-		writeGeneralResponse(w, "error", "User not found", nil, http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 	user.PasswordHash = ""
@@ -166,85 +256,285 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 }
 
 type updateUserInput struct {
-	Username string `json:"username"`
-	Password string `json:"password,omitempty"`
+	Username string `json:"username" example:"user1"`
+}
+
+// Validate проверяет новые данные пользователя
+func (in updateUserInput) Validate() error {
+	v := validation.New()
+	validation.Username(v, "username", in.Username)
+	return v.Err()
 }
 
 // UpdateUser godoc
 // @Summary      Update user by ID
-// @Description  Update user data (password will be hashed if provided)
+// @Description  Update user profile fields (username). Password is changed via /users/{id}/password
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        id    path      int          true  "User ID"
 // @Param        user  body      updateUserInput  true  "Updated user info"
 // @Success      200   {object}  models.GeneralResponse{data=models.User}
 // @Failure      400   {object}  models.GeneralResponse
+// @Failure      401   {object}  models.GeneralResponse
+// @Failure      403   {object}  models.GeneralResponse
+// @Failure      404   {object}  models.GeneralResponse
+// @Failure      409   {object}  models.GeneralResponse
+// @Failure      422   {object}  models.GeneralResponse
 // @Failure      500   {object}  models.GeneralResponse
 // @Router       /users/{id} [put]
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := mux.Vars(r)["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Invalid ID", nil, http.StatusBadRequest)
+		writeError(w, r, invalidID())
+		return
+	}
+	if err := requireSelf(r, id); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	var input updateUserInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeGeneralResponse(w, "error", "Invalid JSON", nil, http.StatusBadRequest)
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	user := models.User{
-		Username: input.Username,
+	// Имя должно быть уникальным; уникальный индекс в БД подстрахует от гонок
+	existing, err := h.Store.GetByUsername(r.Context(), input.Username)
+	if err == nil && existing.ID != id {
+		writeError(w, r, &store.ConflictError{Entity: "user", Field: "username", Reason: "already exists"})
+		return
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		writeError(w, r, err)
+		return
 	}
-	if input.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+
+	var updatedUser models.User
+	err = h.Tx.WithTx(r.Context(), func(tx store.Tx) error {
+		before, err := tx.GetUserByID(r.Context(), id)
 		if err != nil {
-			writeGeneralResponse(w, "error", "Error hashing password", nil, http.StatusInternalServerError)
+			return err
+		}
+		updatedUser, err = tx.UpdateUsername(r.Context(), id, input.Username)
+		if err != nil {
+			return err
+		}
+		entry := audit.Entry(r.Context(), id, audit.UserUpdate, audit.TargetUser, id)
+		return recordAudit(r.Context(), tx, entry, before, updatedUser)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	updatedUser.PasswordHash = ""
+	writeGeneralResponse(w, "success", "User updated", updatedUser, http.StatusOK)
+}
+
+type changePasswordInput struct {
+	CurrentPassword string `json:"current_password" example:"password123"`
+	NewPassword     string `json:"new_password" example:"newPassword456"`
+}
+
+// Validate проверяет запрос на смену пароля
+func (in changePasswordInput) Validate() error {
+	v := validation.New()
+	v.Required("current_password", in.CurrentPassword)
+	validation.Password(v, "new_password", in.NewPassword)
+	v.Check(in.NewPassword != in.CurrentPassword, "new_password", "same_as_current", "new_password must differ from current_password")
+	return v.Err()
+}
+
+// ChangePassword godoc
+// @Summary      Change user password
+// @Description  Change password of the authenticated user; requires the current password
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int                  true  "User ID"
+// @Param        input  body      changePasswordInput  true  "Current and new password"
+// @Success      200    {object}  models.GeneralResponse
+// @Failure      400    {object}  models.GeneralResponse
+// @Failure      401    {object}  models.GeneralResponse
+// @Failure      403    {object}  models.GeneralResponse
+// @Failure      404    {object}  models.GeneralResponse
+// @Failure      422    {object}  models.GeneralResponse
+// @Failure      429    {object}  models.GeneralResponse
+// @Failure      500    {object}  models.GeneralResponse
+// @Router       /users/{id}/password [post]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, invalidID())
+		return
+	}
+	if err := requireSelf(r, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var input changePasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.Store.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Проверка текущего пароля — такой же вход, как /api/login: подбирать
+	// пароль через смену нельзя в обход лимитов и блокировки аккаунта
+	if !h.Guard.allowLogin(w, r, user.Username) {
+		return
+	}
+	errMismatch := store.NewValidationError("current_password", "mismatch", "current_password is incorrect")
+	if user.Locked(time.Now()) {
+		checkDummyPassword(r.Context(), input.CurrentPassword)
+		writeError(w, r, errMismatch)
+		return
+	}
+	if err := checkPassword(r.Context(), user.PasswordHash, input.CurrentPassword); err != nil {
+		if err := h.Guard.loginFailed(r.Context(), h.Store, user); err != nil {
+			writeError(w, r, err)
 			return
 		}
-		user.PasswordHash = string(hashedPassword)
+		writeError(w, r, errMismatch)
+		return
 	}
 
-	updatedUser, err := h.Store.UpdateUser(id, user)
+	hashedPassword, err := hashPassword(r.Context(), input.NewPassword)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to update user", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("hash password: %w", err))
 		return
 	}
 
-	updatedUser.PasswordHash = ""
-	writeGeneralResponse(w, "success", "User updated", updatedUser, http.StatusOK)
+	err = h.Tx.WithTx(r.Context(), func(tx store.Tx) error {
+		if err := tx.UpdatePasswordHash(r.Context(), id, hashedPassword); err != nil {
+			return err
+		}
+		if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+			if err := tx.ResetLoginFailures(r.Context(), id); err != nil {
+				return err
+			}
+		}
+		entry := audit.Entry(r.Context(), id, audit.PasswordChange, audit.TargetUser, id)
+		return recordAudit(r.Context(), tx, entry, nil, nil)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeGeneralResponse(w, "success", "Password changed", nil, http.StatusOK)
 }
 
 // DeleteUser godoc
 // @Summary      Delete user by ID
-// @Description  Delete user by given ID
+// @Description  Delete the caller's own account
 // @Tags         users
 // @Produce      json
+// @Security     BearerAuth
 // @Param        id   path      int  true  "User ID"
 // @Success      200  {object}  models.GeneralResponse
-// @Failure      400  {object}  models.GeneralResponse
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      403  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
 // @Failure      500  {object}  models.GeneralResponse
 // @Router       /users/{id} [delete]
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := mux.Vars(r)["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Invalid ID", nil, http.StatusBadRequest)
+		writeError(w, r, invalidID())
+		return
+	}
+	if err := requireSelf(r, id); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	err = h.Store.DeleteUser(id)
+	// Задачи пользователя удаляются каскадно, поэтому в той же транзакции
+	// снимаем их ссылки на фото
+	photos := photoRefs{files: h.Uploads}
+	err = h.Tx.WithTx(r.Context(), func(tx store.Tx) error {
+		user, err := tx.GetUserByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		deleted := models.User{ID: user.ID, Username: user.Username}
+
+		todos, err := tx.GetTodos(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		// Задачи в корзине тоже держат ссылки на фото
+		trash, err := tx.GetTrash(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		todos = append(todos, trash...)
+
+		for _, todo := range todos {
+			if err := photos.release(r.Context(), tx, todo.PhotoURL); err != nil {
+				return err
+			}
+		}
+		// Журнал отмены держит ссылки на заменённые фото
+		ops, err := tx.DeleteUserTodoOperations(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := releaseOperationPhotos(r.Context(), photos, tx, ops); err != nil {
+			return err
+		}
+
+		// Вебхуки пользователя удаляются вместе с ним; доставки user.deleted
+		// ставятся в очередь до удаления и хранят адрес и секрет у себя
+		if err := webhooks.Enqueue(r.Context(), tx, events.NewUserEvent(events.UserDeleted, deleted)); err != nil {
+			return err
+		}
+		if err := tx.DeleteUser(r.Context(), id); err != nil {
+			return err
+		}
+		entry := audit.Entry(r.Context(), callerID(r), audit.UserDelete, audit.TargetUser, id)
+		return recordAudit(r.Context(), tx, entry, user, nil)
+	})
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to delete user", nil, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	writeGeneralResponse(w, "success", "User deleted", nil, http.StatusOK)
 }
 
+// auditLoginFailure пишет неудачный вход; userID == 0 — пользователь не найден.
+// Ответ клиенту от записи не зависит, поэтому ошибка только логируется.
+func (h *UserHandler) auditLoginFailure(ctx context.Context, userID int) {
+	entry := audit.Entry(ctx, 0, audit.Login, audit.TargetUser, userID)
+	entry.Outcome = audit.Failure
+	if err := recordAudit(ctx, h.Audit, entry, nil, nil); err != nil {
+		logging.FromContext(ctx).Error("failed to record login failure", "user_id", userID, "error", err)
+	}
+}
+
 // RefreshToken godoc
 // @Summary      Refresh JWT tokens
 // @Description  Use refresh token to get new access and refresh tokens
@@ -261,33 +551,37 @@ func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		RefreshToken string `json:"refresh_token"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
-		writeGeneralResponse(w, "error", "Invalid JSON or missing refresh_token", nil, http.StatusBadRequest)
+		writeError(w, r, badRequest("Invalid JSON or missing refresh_token"))
 		return
 	}
 
 	claims, err := auth.ParseJWTToken(req.RefreshToken)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Invalid refresh token", nil, http.StatusUnauthorized)
+		writeError(w, r, unauthorized("Invalid refresh token"))
 		return
 	}
 
 	// Здесь можно проверить, что пользователь существует
-	user, err := h.Store.GetUserByID(claims.UserID)
+	user, err := h.Store.GetUserByID(r.Context(), claims.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, r, unauthorized("User not found"))
+		return
+	}
 	if err != nil {
-		writeGeneralResponse(w, "error", "User not found", nil, http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
 	// Создаем новые токены
 	accessToken, err := auth.CreateJWTToken(user.ID)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to create access token", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("create access token: %w", err))
 		return
 	}
 
 	refreshToken, err := auth.CreateRefreshToken(user.ID)
 	if err != nil {
-		writeGeneralResponse(w, "error", "Failed to create refresh token", nil, http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("create refresh token: %w", err))
 		return
 	}
 
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/audit"
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// userTx — один пользователь в памяти. Остальные методы store.Tx
+// не реализованы: их вызов — ошибка в тесте.
+type userTx struct {
+	store.Tx
+	user    models.User
+	audited []models.AuditEntry
+}
+
+func (tx *userTx) WithTx(_ context.Context, fn func(store.Tx) error) error { return fn(tx) }
+
+func (tx *userTx) GetUserByID(_ context.Context, id int) (models.User, error) {
+	if id != tx.user.ID {
+		return models.User{}, &store.NotFoundError{Entity: "user", ID: id}
+	}
+	return tx.user, nil
+}
+
+func (tx *userTx) UpdatePasswordHash(_ context.Context, _ int, hash string) error {
+	tx.user.PasswordHash = hash
+	return nil
+}
+
+func (tx *userTx) RecordLoginFailure(context.Context, int) (int, error) {
+	tx.user.FailedLoginAttempts++
+	return tx.user.FailedLoginAttempts, nil
+}
+
+func (tx *userTx) LockUser(_ context.Context, _ int, until time.Time) error {
+	tx.user.LockedUntil = &until
+	return nil
+}
+
+func (tx *userTx) ResetLoginFailures(context.Context, int) error {
+	tx.user.FailedLoginAttempts = 0
+	tx.user.LockedUntil = nil
+	return nil
+}
+
+func (tx *userTx) RecordAudit(_ context.Context, entry models.AuditEntry) error {
+	tx.audited = append(tx.audited, entry)
+	return nil
+}
+
+func newUserTx(t *testing.T, password string) *userTx {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &userTx{user: models.User{ID: 7, Username: "alice", PasswordHash: string(hash)}}
+}
+
+func changePassword(t *testing.T, h *UserHandler, current string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"current_password":"` + current + `","new_password":"newPassword456"}`
+	req := authorized(t, httptest.NewRequest(http.MethodPost, "/api/users/7/password", strings.NewReader(body)), 7)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	rec := httptest.NewRecorder()
+	h.ChangePassword(rec, req)
+	return rec
+}
+
+func TestChangePasswordAudited(t *testing.T) {
+	tx := newUserTx(t, "password123")
+	h := NewUserHandler(tx, tx, nil, LoginProtection{}, tx)
+
+	if rec := changePassword(t, h, "password123"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if checkPassword(context.Background(), tx.user.PasswordHash, "newPassword456") != nil {
+		t.Error("password hash was not updated")
+	}
+	if len(tx.audited) != 1 {
+		t.Fatalf("audited %d entries, want 1", len(tx.audited))
+	}
+	entry := tx.audited[0]
+	if entry.Action != audit.PasswordChange || entry.ActorID == nil || *entry.ActorID != 7 || strings.Contains(string(entry.Changes), "hash") {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	tx := newUserTx(t, "password123")
+	guard := LoginProtection{Lockout: auth.Lockout{Threshold: 2, Delay: time.Minute, MaxDelay: time.Hour}}
+	h := NewUserHandler(tx, tx, nil, guard, tx)
+
+	// Неверный текущий пароль считается неудачным входом и ведёт к блокировке;
+	// заблокированный аккаунт не принимает и верный пароль
+	steps := []struct {
+		current string
+		status  int
+	}{
+		{"wrong-password", http.StatusUnprocessableEntity},
+		{"wrong-password", http.StatusUnprocessableEntity},
+		{"password123", http.StatusUnprocessableEntity},
+	}
+	for i, step := range steps {
+		if rec := changePassword(t, h, step.current); rec.Code != step.status {
+			t.Fatalf("step %d: status = %d, want %d: %s", i, rec.Code, step.status, rec.Body)
+		}
+	}
+	if !tx.user.Locked(time.Now()) {
+		t.Error("account is not locked after failed password checks")
+	}
+	if len(tx.audited) != 0 {
+		t.Errorf("password changed while locked: %+v", tx.audited)
+	}
+
+	// После блокировки успешная смена сбрасывает счётчик неудач
+	tx.user.LockedUntil = nil
+	if rec := changePassword(t, h, "password123"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if tx.user.FailedLoginAttempts != 0 {
+		t.Errorf("failed attempts = %d, want reset", tx.user.FailedLoginAttempts)
+	}
+}
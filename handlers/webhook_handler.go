@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/store"
+	"todo-api/validation"
+	"todo-api/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	Store store.WebhookStore
+}
+
+func NewWebhookHandler(store store.WebhookStore) *WebhookHandler {
+	return &WebhookHandler{Store: store}
+}
+
+func (h *WebhookHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/webhooks", h.CreateWebhook).Methods("POST")
+	r.HandleFunc("/api/webhooks", h.GetWebhooks).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}", h.GetWebhook).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}", h.DeleteWebhook).Methods("DELETE")
+	r.HandleFunc("/api/webhooks/{id}/deliveries", h.GetDeliveries).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}/deliveries/{deliveryID}", h.GetDelivery).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}/deliveries/{deliveryID}/redeliver", h.Redeliver).Methods("POST")
+}
+
+type webhookInput struct {
+	URL    string   `json:"url" example:"https://example.com/hooks/todo"`
+	Events []string `json:"events" example:"todo.created,todo.completed"`
+}
+
+// Validate проверяет адрес и список событий
+func (in webhookInput) Validate() error {
+	v := validation.New()
+	validation.URL(v, "url", in.URL)
+	v.Check(len(in.Events) > 0, "events", validation.CodeRequired, "events is required")
+	for _, event := range in.Events {
+		v.Check(webhooks.Supported(event), "events", validation.CodeInvalidValue,
+			"unknown event "+event+"; supported: "+strings.Join(webhooks.Events, ", "))
+	}
+	return v.Err()
+}
+
+// createdWebhook — ответ на создание: секрет показывается только здесь
+type createdWebhook struct {
+	models.Webhook
+	Secret string `json:"secret" example:"whsec_..."`
+}
+
+// ownWebhook загружает вебхук вызывающего пользователя; чужой выглядит как несуществующий
+func (h *WebhookHandler) ownWebhook(r *http.Request) (models.Webhook, error) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		return models.Webhook{}, unauthorized("Unauthorized")
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return models.Webhook{}, invalidID()
+	}
+
+	hook, err := h.Store.GetWebhookByID(r.Context(), id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if hook.UserID != userID {
+		return models.Webhook{}, &store.NotFoundError{Entity: "webhook", ID: id}
+	}
+	return hook, nil
+}
+
+// ownDelivery загружает доставку вебхука вызывающего пользователя
+func (h *WebhookHandler) ownDelivery(r *http.Request) (models.WebhookDelivery, error) {
+	hook, err := h.ownWebhook(r)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["deliveryID"], 10, 64)
+	if err != nil {
+		return models.WebhookDelivery{}, store.NewValidationError("deliveryID", "invalid", "deliveryID must be an integer")
+	}
+
+	delivery, err := h.Store.GetWebhookDelivery(r.Context(), id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if delivery.WebhookID == nil || *delivery.WebhookID != hook.ID {
+		return models.WebhookDelivery{}, &store.NotFoundError{Entity: "webhook_delivery", ID: id}
+	}
+	return delivery, nil
+}
+
+// CreateWebhook godoc
+// @Summary      Register a webhook
+// @Description  Register an endpoint for todo.created, todo.updated, todo.completed, todo.deleted, todo.restored and user.deleted events.
+// @Description  Each delivery is a POST with X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and
+// @Description  X-Webhook-Signature = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// @Description  The secret is returned only once, in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        webhook  body      webhookInput  true  "Endpoint URL and events"
+// @Success      201      {object}  models.GeneralResponse{data=createdWebhook}
+// @Failure      400      {object}  models.GeneralResponse
+// @Failure      401      {object}  models.GeneralResponse
+// @Failure      422      {object}  models.GeneralResponse
+// @Failure      500      {object}  models.GeneralResponse
+// @Router       /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	var input webhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, r, badRequest("Invalid JSON"))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		writeError(w, r, fmt.Errorf("generate webhook secret: %w", err))
+		return
+	}
+
+	slices.Sort(input.Events)
+	hook, err := h.Store.CreateWebhook(r.Context(), models.Webhook{
+		UserID: userID,
+		URL:    input.URL,
+		Events: slices.Compact(input.Events),
+		Secret: secret,
+		Active: true,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Webhook created", createdWebhook{Webhook: hook, Secret: secret}, http.StatusCreated)
+}
+
+// GetWebhooks godoc
+// @Summary      List webhooks
+// @Description  List webhooks of the authenticated user
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.GeneralResponse{data=[]models.Webhook}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      500  {object}  models.GeneralResponse
+// @Router       /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, unauthorized("Unauthorized"))
+		return
+	}
+
+	hooks, err := h.Store.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+	writeGeneralResponse(w, "success", "Webhooks fetched", hooks, http.StatusOK)
+}
+
+// GetWebhook godoc
+// @Summary      Get a webhook
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  models.GeneralResponse{data=models.Webhook}
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.ownWebhook(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Webhook fetched", hook, http.StatusOK)
+}
+
+// DeleteWebhook godoc
+// @Summary      Delete a webhook
+// @Description  Delete a webhook together with its delivery log; pending deliveries are dropped
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  models.GeneralResponse
+// @Failure      401  {object}  models.GeneralResponse
+// @Failure      404  {object}  models.GeneralResponse
+// @Failure      422  {object}  models.GeneralResponse
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.ownWebhook(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.Store.DeleteWebhook(r.Context(), hook.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Webhook deleted", nil, http.StatusOK)
+}
+
+// GetDeliveries godoc
+// @Summary      List webhook deliveries
+// @Description  Delivery log of a webhook, newest first
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int  true   "Webhook ID"
+// @Param        limit   query     int  false  "Page size (1-100, default 20)"
+// @Param        offset  query     int  false  "Offset"
+// @Success      200     {object}  models.GeneralResponse{data=[]models.WebhookDelivery}
+// @Failure      401     {object}  models.GeneralResponse
+// @Failure      404     {object}  models.GeneralResponse
+// @Failure      422     {object}  models.GeneralResponse
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.ownWebhook(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	p, err := parsePage(r, 20, 100)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveries, err := h.Store.GetWebhookDeliveries(r.Context(), hook.ID, p.Limit, p.Offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	writeGeneralResponse(w, "success", "Deliveries fetched", deliveries, http.StatusOK)
+}
+
+// GetDelivery godoc
+// @Summary      Get a webhook delivery
+// @Description  Delivery with its attempt log
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path      int  true  "Webhook ID"
+// @Param        deliveryID  path      int  true  "Delivery ID"
+// @Success      200         {object}  models.GeneralResponse{data=models.WebhookDelivery}
+// @Failure      401         {object}  models.GeneralResponse
+// @Failure      404         {object}  models.GeneralResponse
+// @Failure      422         {object}  models.GeneralResponse
+// @Router       /webhooks/{id}/deliveries/{deliveryID} [get]
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.ownDelivery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Delivery fetched", delivery, http.StatusOK)
+}
+
+// Redeliver godoc
+// @Summary      Redeliver a webhook delivery
+// @Description  Put a delivery (including a dead one) back into the queue with a fresh attempt budget
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path      int  true  "Webhook ID"
+// @Param        deliveryID  path      int  true  "Delivery ID"
+// @Success      202         {object}  models.GeneralResponse{data=models.WebhookDelivery}
+// @Failure      401         {object}  models.GeneralResponse
+// @Failure      404         {object}  models.GeneralResponse
+// @Failure      422         {object}  models.GeneralResponse
+// @Router       /webhooks/{id}/deliveries/{deliveryID}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.ownDelivery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	delivery, err = h.Store.RedeliverWebhook(r.Context(), delivery.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeGeneralResponse(w, "success", "Delivery queued", delivery, http.StatusAccepted)
+}
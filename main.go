@@ -4,15 +4,38 @@
 
 // @host      localhost:8080
 // @BasePath  /api
+// @schemes   http https
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"todo-api/config"
 	"todo-api/db"
+	"todo-api/events"
 	"todo-api/handlers"
+	"todo-api/logging"
+	"todo-api/metrics"
+	"todo-api/middleware"
+	"todo-api/ratelimit"
+	"todo-api/tlsutil"
+	"todo-api/tracing"
+	"todo-api/uploads"
+	"todo-api/webhooks"
 
 	_ "todo-api/docs"
 
@@ -21,27 +44,352 @@ import (
 )
 
 func main() {
-	connStr := "host=localhost port=5432 user=postgres password=nmkl2018 dbname=todo_db sslmode=disable"
-	store := db.NewPostgresStore(connStr)
+	cfg := config.Load()
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level))
+
+	// Одноразовые команды: todo-api gc-uploads [-dry-run], todo-api set-role <username> <role>
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gc-uploads":
+			os.Exit(runUploadGC(cfg, os.Args[2:]))
+		case "set-role":
+			os.Exit(runSetRole(cfg, os.Args[2:]))
+		}
+	}
+
+	if err := run(cfg); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run поднимает сервис и блокируется до SIGINT/SIGTERM. Остановка идёт по порядку:
+// HTTP-сервер дожидается активных запросов, затем фоновые задачи, затем пул БД.
+func run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	store, err := db.NewPostgresStore(ctx, cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to DB: %w", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("failed to close DB pool", "error", err)
+		}
+		slog.Info("DB pool closed")
+	}()
+	store.PublishPoolStats("db_pool")
+	metrics.RegisterDBStats(store.DB.DB, "todo_db")
+
+	if err := store.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	files := uploads.NewLocalStorage(cfg.UploadsDir, cfg.UploadsURL)
+
+	// Разделяем хранилища; каждое обёрнуто в декораторы с метриками и трейсингом
+	todos := metrics.TodoStore{Next: tracing.TodoStore{Next: store}}
+	users := metrics.UserStore{Next: tracing.UserStore{Next: store}}
+	blobs := metrics.UploadStore{Next: tracing.UploadStore{Next: store}}
+	hooks := metrics.WebhookStore{Next: tracing.WebhookStore{Next: store}}
+	auditLog := metrics.AuditStore{Next: tracing.AuditStore{Next: store}}
+	filters := metrics.FilterStore{Next: tracing.FilterStore{Next: store}}
+	tx := metrics.Transactor{Next: tracing.Transactor{Next: store}}
+
+	limits, err := newRateLimits(cfg.RateLimit, cfg.HTTP.TrustProxy, store)
+	if err != nil {
+		return err
+	}
+
+	// Поток изменений задач: хаб в процессе, между инстансами — через LISTEN/NOTIFY
+	hub := events.NewHub(cfg.Events.LogSize, cfg.Events.BufferSize)
+	var (
+		publisher events.Publisher = hub
+		bridge    *events.PostgresBridge
+	)
+	switch cfg.Events.Backend {
+	case "memory":
+	case "postgres":
+		bridge = &events.PostgresBridge{Hub: hub, Notifier: store, Channel: cfg.Events.Channel}
+		publisher = bridge
+	default:
+		return fmt.Errorf("unknown EVENTS_BACKEND %q (want memory or postgres)", cfg.Events.Backend)
+	}
+	// Те же события ставятся в очередь исходящих вебхуков. Публикует их relay
+	// из outbox, куда хранилище пишет события вместе с изменениями.
+	publisher = events.Multi{publisher, webhooks.Enqueuer{Store: hooks}}
+	relay := events.NewRelay(metrics.OutboxStore{Next: tracing.OutboxStore{Next: store}}, publisher, events.RelayOptions{
+		BatchSize: cfg.Outbox.BatchSize,
+		Lease:     cfg.Outbox.Lease,
+		RetryBase: cfg.Outbox.RetryBase,
+		RetryMax:  cfg.Outbox.RetryMax,
+		Retention: cfg.Outbox.Retention,
+	})
+
+	todoHandler := handlers.NewTodoHandler(todos, tx, files, handlers.TodoStream{Hub: hub, Heartbeat: cfg.Events.Heartbeat}, cfg.Undo.Window)
+	todoSocket := handlers.NewTodoSocket(todoHandler, hub, handlers.SocketOptions{
+		AllowedOrigins:  cfg.CORS.AllowedOrigins,
+		MessageRate:     ratelimit.Rate{Limit: cfg.WebSocket.MessagesPerWindow, Per: cfg.WebSocket.MessageWindow},
+		MaxMessageBytes: cfg.WebSocket.MaxMessageBytes,
+		SendBuffer:      cfg.WebSocket.SendBuffer,
+		PingInterval:    cfg.WebSocket.PingInterval,
+	})
+	userHandler := handlers.NewUserHandler(users, tx, files, limits.guard, auditLog)
+	webhookHandler := handlers.NewWebhookHandler(hooks)
+	auditHandler := handlers.NewAuditHandler(auditLog, users)
+	filterHandler := handlers.NewFilterHandler(filters, todos)
+	healthHandler := handlers.NewHealthHandler(store, files, db.LatestSchemaVersion())
+
+	// Фоновые задачи живут до начала остановки
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+		slog.Info("background workers stopped")
+	}()
+
+	// Фоновая чистка осиротевших файлов
+	if cfg.UploadGC.Enabled {
+		collector := uploads.NewCollector(files, blobs, tx, cfg.UploadGC.GracePeriod, cfg.UploadGC.DryRun)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			collector.Run(workersCtx, cfg.UploadGC.Interval)
+		}()
+	}
+
+	if bridge != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := db.Listen(workersCtx, cfg.DB.URL, cfg.Events.Channel, bridge.Receive); err != nil {
+				slog.Error("event listener stopped", "channel", cfg.Events.Channel, "error", err)
+			}
+		}()
+	}
 
-	// Разделяем хранилища
-	todoHandler := handlers.NewTodoHandler(store)
-	userHandler := handlers.NewUserHandler(store)
+	// Рассылка событий из outbox; триггер будит relay сразу после коммита
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		relay.Run(workersCtx, cfg.Outbox.Interval)
+	}()
+	go func() {
+		defer workers.Done()
+		if err := db.Listen(workersCtx, cfg.DB.URL, db.OutboxChannel, func(string) { relay.Wake() }); err != nil {
+			slog.Error("outbox listener stopped", "error", err)
+		}
+	}()
+
+	// Отправка вебхуков; несколько инстансов делят очередь через SKIP LOCKED
+	if cfg.Webhooks.Enabled {
+		dispatcher := &webhooks.Dispatcher{
+			Store:       hooks,
+			Client:      webhooks.NewHTTPClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivate),
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			BackoffBase: cfg.Webhooks.BackoffBase,
+			BackoffMax:  cfg.Webhooks.BackoffMax,
+			BatchSize:   cfg.Webhooks.BatchSize,
+			Lease:       cfg.Webhooks.Lease,
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workersCtx, cfg.Webhooks.Interval)
+		}()
+	}
+
+	// Окончательное удаление задач из корзины вместе с файлами
+	if cfg.Trash.Retention > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			todoHandler.RunTrashRetention(workersCtx, cfg.Trash.Interval, cfg.Trash.Retention, cfg.Trash.BatchSize)
+		}()
+	}
+
+	// Журнал отмены: истёкшие записи удаляются и отпускают старые фото.
+	// Работает и при выключенной отмене, чтобы дочистить прежние записи.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		todoHandler.RunUndoExpiry(workersCtx, cfg.Undo.Interval, cfg.Undo.BatchSize)
+	}()
+
+	// Чистка вёдер rate limiting
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		limits.cleanup(workersCtx)
+	}()
 
 	// Роутер
 	r := mux.NewRouter()
+	chain := []mux.MiddlewareFunc{tracing.Middleware, middleware.AccessLog, metrics.Middleware,
+		middleware.DBDeadline(cfg.DB.RequestTimeout)}
+	if limits.api != nil {
+		chain = append(chain, ratelimit.Middleware(limits.api, cfg.HTTP.TrustProxy))
+	}
+	r.Use(chain...)
+	// mux не пропускает 404 и 405 через r.Use — оборачиваем их той же цепочкой
+	r.NotFoundHandler = wrap(http.NotFoundHandler(), chain)
+	r.MethodNotAllowedHandler = wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}), chain)
 
 	// Регистрируем маршруты
 	todoHandler.RegisterRoutes(r)
+	todoSocket.RegisterRoutes(r)
 	userHandler.RegisterRoutes(r)
+	webhookHandler.RegisterRoutes(r)
+	auditHandler.RegisterRoutes(r)
+	filterHandler.RegisterRoutes(r)
+	healthHandler.RegisterRoutes(r)
 
-	// Разрешаем отдавать статические файлы из папки "./uploads"
+	// Разрешаем отдавать статические файлы из папки uploads
 	// Файлы будут доступны по пути: http://localhost:8080/uploads/<filename>
-	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	r.PathPrefix("/uploads/").Handler(middleware.ContentSecurityPolicy(cfg.Security.UploadsCSP,
+		http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.UploadsDir)))))
 
 	// Swagger
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	fmt.Println("🚀 Сервер запущен на http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	// CORS и защитные заголовки оборачивают роутер целиком, чтобы покрыть
+	// preflight-запросы и ответы 404/405
+	var handler http.Handler = r
+	handler = middleware.CORS(cfg.CORS)(handler)
+	handler = middleware.SecurityHeaders(cfg.Security, cfg.HTTP.TrustProxy)(handler)
+	handler = middleware.RequestOrigin(cfg.HTTP.TrustProxy, cfg.HTTP.PublicOrigins)(handler)
+	handler = middleware.RequestID(handler)
+
+	srv := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	// Нативный TLS: сертификат перечитывается при обновлении файлов без перезапуска
+	if cfg.HTTP.TLS.Enabled() {
+		reloader, err := tlsutil.NewCertReloader(cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		if srv.TLSConfig, err = tlsutil.ServerConfig(cfg.HTTP.TLS, reloader); err != nil {
+			return fmt.Errorf("invalid TLS config: %w", err)
+		}
+		if cfg.HTTP.TLS.ReloadInterval > 0 {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				reloader.Run(workersCtx, cfg.HTTP.TLS.ReloadInterval)
+			}()
+		}
+	}
+
+	// Открытые SSE-потоки и WebSocket-соединения сами не завершатся — закрываем их при остановке
+	srv.RegisterOnShutdown(hub.Close)
+	srv.RegisterOnShutdown(todoSocket.Shutdown)
+
+	serverErr := make(chan error, 3)
+	go func() {
+		slog.Info("server started", "addr", cfg.HTTP.Addr, "tls", srv.TLSConfig != nil)
+		if srv.TLSConfig != nil {
+			serverErr <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// HTTP-listener, отправляющий клиентов на HTTPS
+	var redirectSrv *http.Server
+	if srv.TLSConfig != nil && cfg.HTTP.TLS.RedirectAddr != "" {
+		redirectSrv = &http.Server{
+			Addr:              cfg.HTTP.TLS.RedirectAddr,
+			Handler:           tlsutil.RedirectHandler(cfg.HTTP.Addr),
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			ErrorLog:          srv.ErrorLog,
+		}
+		go func() {
+			slog.Info("HTTPS redirect started", "addr", redirectSrv.Addr)
+			serverErr <- redirectSrv.ListenAndServe()
+		}()
+	}
+
+	// Внутренние метрики: expvar и Prometheus — отдельным listener'ом, чтобы
+	// не отдавать наружу состояние пулов, очередей и пользователей
+	var metricsSrv *http.Server
+	if cfg.HTTP.MetricsAddr != "" {
+		internal := http.NewServeMux()
+		internal.Handle("/debug/vars", expvar.Handler())
+		internal.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{
+			Addr:              cfg.HTTP.MetricsAddr,
+			Handler:           internal,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			ErrorLog:          srv.ErrorLog,
+		}
+		go func() {
+			slog.Info("metrics server started", "addr", metricsSrv.Addr)
+			serverErr <- metricsSrv.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server stopped: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", cfg.HTTP.ShutdownTimeout)
+	stop() // повторный сигнал завершит процесс сразу
+	healthHandler.SetShuttingDown()
+	time.Sleep(cfg.HTTP.ShutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if redirectSrv != nil {
+		redirectSrv.Shutdown(shutdownCtx)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown timed out, closing connections", "error", err)
+		srv.Close()
+	}
+	// Метрики отдаём до последнего запроса
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+	slog.Info("HTTP server stopped")
+	return nil
+}
+
+// wrap оборачивает h цепочкой middleware в том же порядке, что и mux.Router.Use
+func wrap(h http.Handler, chain []mux.MiddlewareFunc) http.Handler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h
 }
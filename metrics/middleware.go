@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-api/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware считает запросы и их длительность. В метку route идёт шаблон
+// маршрута mux (/api/todos/{id}), а не сырой путь, чтобы не плодить серии.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := middleware.NewResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		labels := []string{route, r.Method, strconv.Itoa(rec.Status())}
+		HTTPRequests.WithLabelValues(labels...).Inc()
+		HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
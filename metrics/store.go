@@ -0,0 +1,361 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// Декораторы хранилищ: замеряют длительность каждого метода.
+// Использование: metrics.TodoStore{Next: pg} вместо pg.
+
+// observe вызывается через defer в начале метода; err читается уже после возврата
+func observe(method string, start time.Time, err *error) {
+	DBQueryDuration.WithLabelValues(method, outcome(*err)).Observe(time.Since(start).Seconds())
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, store.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, store.ErrConflict):
+		return "conflict"
+	case errors.Is(err, store.ErrValidation):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
+type TodoStore struct {
+	Next store.TodoStore
+}
+
+func (s TodoStore) GetTodos(ctx context.Context, userID int) (_ []models.Todo, err error) {
+	defer observe("GetTodos", time.Now(), &err)
+	return s.Next.GetTodos(ctx, userID)
+}
+
+func (s TodoStore) CreateTodo(ctx context.Context, todo models.Todo) (_ models.Todo, err error) {
+	defer observe("CreateTodo", time.Now(), &err)
+	return s.Next.CreateTodo(ctx, todo)
+}
+
+func (s TodoStore) UpdateTodo(ctx context.Context, id int, updated models.Todo) (_ models.Todo, err error) {
+	defer observe("UpdateTodo", time.Now(), &err)
+	return s.Next.UpdateTodo(ctx, id, updated)
+}
+
+func (s TodoStore) DeleteTodo(ctx context.Context, id int) (err error) {
+	defer observe("DeleteTodo", time.Now(), &err)
+	return s.Next.DeleteTodo(ctx, id)
+}
+
+func (s TodoStore) GetTodoByID(ctx context.Context, id int) (_ models.Todo, err error) {
+	defer observe("GetTodoByID", time.Now(), &err)
+	return s.Next.GetTodoByID(ctx, id)
+}
+
+func (s TodoStore) FilterTodos(ctx context.Context, userID int, filter models.TodoFilter, limit, offset int) (_ []models.Todo, err error) {
+	defer observe("FilterTodos", time.Now(), &err)
+	return s.Next.FilterTodos(ctx, userID, filter, limit, offset)
+}
+
+func (s TodoStore) SearchTodos(ctx context.Context, userID int, query string, limit, offset int) (_ []models.TodoSearchResult, err error) {
+	defer observe("SearchTodos", time.Now(), &err)
+	return s.Next.SearchTodos(ctx, userID, query, limit, offset)
+}
+
+func (s TodoStore) LockTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	defer observe("LockTodo", time.Now(), &err)
+	return s.Next.LockTodo(ctx, id)
+}
+
+func (s TodoStore) GetTrash(ctx context.Context, userID int) (_ []models.Todo, err error) {
+	defer observe("GetTrash", time.Now(), &err)
+	return s.Next.GetTrash(ctx, userID)
+}
+
+func (s TodoStore) GetDeletedTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	defer observe("GetDeletedTodo", time.Now(), &err)
+	return s.Next.GetDeletedTodo(ctx, id)
+}
+
+func (s TodoStore) RestoreTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	defer observe("RestoreTodo", time.Now(), &err)
+	return s.Next.RestoreTodo(ctx, id)
+}
+
+func (s TodoStore) PurgeTodo(ctx context.Context, id int) (err error) {
+	defer observe("PurgeTodo", time.Now(), &err)
+	return s.Next.PurgeTodo(ctx, id)
+}
+
+func (s TodoStore) GetExpiredTrash(ctx context.Context, olderThan time.Duration, limit int) (_ []models.Todo, err error) {
+	defer observe("GetExpiredTrash", time.Now(), &err)
+	return s.Next.GetExpiredTrash(ctx, olderThan, limit)
+}
+
+type UserStore struct {
+	Next store.UserStore
+}
+
+func (s UserStore) GetUsers(ctx context.Context) (_ []models.User, err error) {
+	defer observe("GetUsers", time.Now(), &err)
+	return s.Next.GetUsers(ctx)
+}
+
+func (s UserStore) CreateUser(ctx context.Context, user models.User) (_ models.User, err error) {
+	defer observe("CreateUser", time.Now(), &err)
+	return s.Next.CreateUser(ctx, user)
+}
+
+func (s UserStore) UpdateUsername(ctx context.Context, id int, username string) (_ models.User, err error) {
+	defer observe("UpdateUsername", time.Now(), &err)
+	return s.Next.UpdateUsername(ctx, id, username)
+}
+
+func (s UserStore) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) (err error) {
+	defer observe("UpdatePasswordHash", time.Now(), &err)
+	return s.Next.UpdatePasswordHash(ctx, id, passwordHash)
+}
+
+func (s UserStore) DeleteUser(ctx context.Context, id int) (err error) {
+	defer observe("DeleteUser", time.Now(), &err)
+	return s.Next.DeleteUser(ctx, id)
+}
+
+func (s UserStore) GetUserByID(ctx context.Context, id int) (_ models.User, err error) {
+	defer observe("GetUserByID", time.Now(), &err)
+	return s.Next.GetUserByID(ctx, id)
+}
+
+func (s UserStore) GetByUsername(ctx context.Context, username string) (_ models.User, err error) {
+	defer observe("GetByUsername", time.Now(), &err)
+	return s.Next.GetByUsername(ctx, username)
+}
+
+func (s UserStore) SetUserRole(ctx context.Context, id int, role string) (_ models.User, err error) {
+	defer observe("SetUserRole", time.Now(), &err)
+	return s.Next.SetUserRole(ctx, id, role)
+}
+
+func (s UserStore) RecordLoginFailure(ctx context.Context, id int) (_ int, err error) {
+	defer observe("RecordLoginFailure", time.Now(), &err)
+	return s.Next.RecordLoginFailure(ctx, id)
+}
+
+func (s UserStore) LockUser(ctx context.Context, id int, until time.Time) (err error) {
+	defer observe("LockUser", time.Now(), &err)
+	return s.Next.LockUser(ctx, id, until)
+}
+
+func (s UserStore) ResetLoginFailures(ctx context.Context, id int) (err error) {
+	defer observe("ResetLoginFailures", time.Now(), &err)
+	return s.Next.ResetLoginFailures(ctx, id)
+}
+
+type UploadStore struct {
+	Next store.UploadStore
+}
+
+func (s UploadStore) GetPhotoURLs(ctx context.Context) (_ []string, err error) {
+	defer observe("GetPhotoURLs", time.Now(), &err)
+	return s.Next.GetPhotoURLs(ctx)
+}
+
+func (s UploadStore) GetBlobKeys(ctx context.Context) (_ []string, err error) {
+	defer observe("GetBlobKeys", time.Now(), &err)
+	return s.Next.GetBlobKeys(ctx)
+}
+
+func (s UploadStore) AcquireBlob(ctx context.Context, key string, size int64) (_ models.Blob, err error) {
+	defer observe("AcquireBlob", time.Now(), &err)
+	return s.Next.AcquireBlob(ctx, key, size)
+}
+
+func (s UploadStore) ReleaseBlob(ctx context.Context, key string) (err error) {
+	defer observe("ReleaseBlob", time.Now(), &err)
+	return s.Next.ReleaseBlob(ctx, key)
+}
+
+func (s UploadStore) ClaimUnreferencedBlob(ctx context.Context, key string) (_ bool, err error) {
+	defer observe("ClaimUnreferencedBlob", time.Now(), &err)
+	return s.Next.ClaimUnreferencedBlob(ctx, key)
+}
+
+type WebhookStore struct {
+	Next store.WebhookStore
+}
+
+func (s WebhookStore) CreateWebhook(ctx context.Context, hook models.Webhook) (_ models.Webhook, err error) {
+	defer observe("CreateWebhook", time.Now(), &err)
+	return s.Next.CreateWebhook(ctx, hook)
+}
+
+func (s WebhookStore) GetWebhooks(ctx context.Context, userID int) (_ []models.Webhook, err error) {
+	defer observe("GetWebhooks", time.Now(), &err)
+	return s.Next.GetWebhooks(ctx, userID)
+}
+
+func (s WebhookStore) GetWebhookByID(ctx context.Context, id int) (_ models.Webhook, err error) {
+	defer observe("GetWebhookByID", time.Now(), &err)
+	return s.Next.GetWebhookByID(ctx, id)
+}
+
+func (s WebhookStore) DeleteWebhook(ctx context.Context, id int) (err error) {
+	defer observe("DeleteWebhook", time.Now(), &err)
+	return s.Next.DeleteWebhook(ctx, id)
+}
+
+func (s WebhookStore) EnqueueWebhookDeliveries(ctx context.Context, userID int, event string, payload []byte) (_ int64, err error) {
+	defer observe("EnqueueWebhookDeliveries", time.Now(), &err)
+	return s.Next.EnqueueWebhookDeliveries(ctx, userID, event, payload)
+}
+
+func (s WebhookStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []models.DueDelivery, err error) {
+	defer observe("ClaimWebhookDeliveries", time.Now(), &err)
+	return s.Next.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (s WebhookStore) RecordWebhookAttempt(ctx context.Context, id int64, attempt models.WebhookAttempt, status string, nextAttemptAt time.Time) (err error) {
+	defer observe("RecordWebhookAttempt", time.Now(), &err)
+	return s.Next.RecordWebhookAttempt(ctx, id, attempt, status, nextAttemptAt)
+}
+
+func (s WebhookStore) GetWebhookDeliveries(ctx context.Context, webhookID, limit, offset int) (_ []models.WebhookDelivery, err error) {
+	defer observe("GetWebhookDeliveries", time.Now(), &err)
+	return s.Next.GetWebhookDeliveries(ctx, webhookID, limit, offset)
+}
+
+func (s WebhookStore) GetWebhookDelivery(ctx context.Context, id int64) (_ models.WebhookDelivery, err error) {
+	defer observe("GetWebhookDelivery", time.Now(), &err)
+	return s.Next.GetWebhookDelivery(ctx, id)
+}
+
+func (s WebhookStore) RedeliverWebhook(ctx context.Context, id int64) (_ models.WebhookDelivery, err error) {
+	defer observe("RedeliverWebhook", time.Now(), &err)
+	return s.Next.RedeliverWebhook(ctx, id)
+}
+
+type OutboxStore struct {
+	Next store.OutboxStore
+}
+
+func (s OutboxStore) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) (_ []models.OutboxEvent, err error) {
+	defer observe("ClaimOutboxEvents", time.Now(), &err)
+	return s.Next.ClaimOutboxEvents(ctx, limit, lease)
+}
+
+func (s OutboxStore) MarkOutboxProcessed(ctx context.Context, id int64) (err error) {
+	defer observe("MarkOutboxProcessed", time.Now(), &err)
+	return s.Next.MarkOutboxProcessed(ctx, id)
+}
+
+func (s OutboxStore) RetryOutboxEvent(ctx context.Context, id int64, lastError string, retryAt time.Time) (err error) {
+	defer observe("RetryOutboxEvent", time.Now(), &err)
+	return s.Next.RetryOutboxEvent(ctx, id, lastError, retryAt)
+}
+
+func (s OutboxStore) PruneOutbox(ctx context.Context, olderThan time.Duration) (_ int64, err error) {
+	defer observe("PruneOutbox", time.Now(), &err)
+	return s.Next.PruneOutbox(ctx, olderThan)
+}
+
+type AuditStore struct {
+	Next store.AuditStore
+}
+
+func (s AuditStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (err error) {
+	defer observe("RecordAudit", time.Now(), &err)
+	return s.Next.RecordAudit(ctx, entry)
+}
+
+func (s AuditStore) GetAuditEntries(ctx context.Context, filter models.AuditFilter) (_ []models.AuditEntry, err error) {
+	defer observe("GetAuditEntries", time.Now(), &err)
+	return s.Next.GetAuditEntries(ctx, filter)
+}
+
+type UndoStore struct {
+	Next store.UndoStore
+}
+
+func (s UndoStore) RecordTodoOperation(ctx context.Context, op models.TodoOperation) (_ models.TodoOperation, err error) {
+	defer observe("RecordTodoOperation", time.Now(), &err)
+	return s.Next.RecordTodoOperation(ctx, op)
+}
+
+func (s UndoStore) GetTodoOperation(ctx context.Context, token string) (_ models.TodoOperation, err error) {
+	defer observe("GetTodoOperation", time.Now(), &err)
+	return s.Next.GetTodoOperation(ctx, token)
+}
+
+func (s UndoStore) MarkTodoOperationUndone(ctx context.Context, id int64) (err error) {
+	defer observe("MarkTodoOperationUndone", time.Now(), &err)
+	return s.Next.MarkTodoOperationUndone(ctx, id)
+}
+
+func (s UndoStore) TakeExpiredTodoOperations(ctx context.Context, limit int) (_ []models.TodoOperation, err error) {
+	defer observe("TakeExpiredTodoOperations", time.Now(), &err)
+	return s.Next.TakeExpiredTodoOperations(ctx, limit)
+}
+
+func (s UndoStore) DeleteUserTodoOperations(ctx context.Context, userID int) (_ []models.TodoOperation, err error) {
+	defer observe("DeleteUserTodoOperations", time.Now(), &err)
+	return s.Next.DeleteUserTodoOperations(ctx, userID)
+}
+
+type FilterStore struct {
+	Next store.FilterStore
+}
+
+func (s FilterStore) CreateFilter(ctx context.Context, filter models.SavedFilter) (_ models.SavedFilter, err error) {
+	defer observe("CreateFilter", time.Now(), &err)
+	return s.Next.CreateFilter(ctx, filter)
+}
+
+func (s FilterStore) GetFilters(ctx context.Context, userID int) (_ []models.SavedFilter, err error) {
+	defer observe("GetFilters", time.Now(), &err)
+	return s.Next.GetFilters(ctx, userID)
+}
+
+func (s FilterStore) GetFilterByID(ctx context.Context, id int) (_ models.SavedFilter, err error) {
+	defer observe("GetFilterByID", time.Now(), &err)
+	return s.Next.GetFilterByID(ctx, id)
+}
+
+func (s FilterStore) UpdateFilter(ctx context.Context, id int, filter models.SavedFilter) (_ models.SavedFilter, err error) {
+	defer observe("UpdateFilter", time.Now(), &err)
+	return s.Next.UpdateFilter(ctx, id, filter)
+}
+
+func (s FilterStore) DeleteFilter(ctx context.Context, id int) (err error) {
+	defer observe("DeleteFilter", time.Now(), &err)
+	return s.Next.DeleteFilter(ctx, id)
+}
+
+// Transactor замеряет транзакцию целиком и оборачивает методы внутри неё
+type Transactor struct {
+	Next store.Transactor
+}
+
+func (t Transactor) WithTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	defer observe("WithTx", time.Now(), &err)
+	return t.Next.WithTx(ctx, func(tx store.Tx) error {
+		return fn(txStore{TodoStore{tx}, UserStore{tx}, UploadStore{tx}, WebhookStore{tx}, AuditStore{tx}, UndoStore{tx}})
+	})
+}
+
+type txStore struct {
+	TodoStore
+	UserStore
+	UploadStore
+	WebhookStore
+	AuditStore
+	UndoStore
+}
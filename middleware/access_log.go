@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/logging"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessLog пишет по строке на каждый запрос: метод, маршрут, статус,
+// время обработки, размер ответа и пользователя (если есть токен)
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := NewResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int64("bytes", rec.Bytes()),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				attrs = append(attrs, slog.String("route", tpl))
+			}
+		}
+		if userID, err := auth.ExtractUserIDFromRequest(r); err == nil {
+			attrs = append(attrs, slog.Int("user_id", userID))
+		}
+		// Сервис-клиент, представившийся сертификатом (mTLS)
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			attrs = append(attrs, slog.String("client_cert", r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+
+		level := slog.LevelInfo
+		if rec.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(r.Context()).LogAttrs(r.Context(), level, "http request", attrs...)
+	})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"todo-api/store"
+)
+
+// DBDeadline даёт всем обращениям к БД в рамках запроса один общий дедлайн:
+// timeout отсчитывается от начала запроса, а не заново для каждого SQL-запроса.
+// Долгие ответы (SSE, WebSocket) не обрываются — истекает только доступ к БД.
+// timeout <= 0 отключает ограничение.
+func DBDeadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := store.WithDeadline(r.Context(), time.Now().Add(timeout))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
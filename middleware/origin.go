@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"todo-api/ratelimit"
+)
+
+type (
+	originKey   struct{}
+	clientIPKey struct{}
+)
+
+// RequestOrigin запоминает в контексте origin запроса (схема и хост, например
+// https://api.example.com), по которому клиент обратился к сервису, и адрес
+// клиента. За доверенным прокси учитываются X-Forwarded-Proto, X-Forwarded-Host
+// и X-Forwarded-For. Хост присылает клиент, поэтому origin запоминается, только
+// если он есть в publicOrigins.
+func RequestOrigin(trustProxy bool, publicOrigins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(publicOrigins))
+	for _, o := range publicOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(o, "/"))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := "http"
+			if IsHTTPS(r, trustProxy) {
+				scheme = "https"
+			}
+			host := r.Host
+			if fwd := r.Header.Get("X-Forwarded-Host"); trustProxy && fwd != "" {
+				host = fwd
+			}
+
+			ctx := r.Context()
+			if origin := strings.ToLower(scheme + "://" + host); allowed[origin] {
+				ctx = context.WithValue(ctx, originKey{}, origin)
+			}
+			ctx = context.WithValue(ctx, clientIPKey{}, ratelimit.ClientIP(r, trustProxy))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetOrigin возвращает origin текущего запроса или "", если он не известен
+// или не входит в список публичных
+func GetOrigin(ctx context.Context) string {
+	origin, _ := ctx.Value(originKey{}).(string)
+	return origin
+}
+
+// GetClientIP возвращает адрес клиента текущего запроса или "", если он не известен
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestOrigin(t *testing.T) {
+	public := []string{"https://api.example.com", "http://LOCALHOST:8080/"}
+	tests := []struct {
+		name       string
+		host       string
+		headers    map[string]string
+		trustProxy bool
+		want       string
+	}{
+		{name: "public origin", host: "localhost:8080", want: "http://localhost:8080"},
+		{name: "spoofed host", host: "evil.example", want: ""},
+		{
+			name: "forwarded by trusted proxy", host: "10.0.0.5",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			trustProxy: true, want: "https://api.example.com",
+		},
+		{
+			name: "forwarded host spoofed behind proxy", host: "api.example.com",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			trustProxy: true, want: "",
+		},
+		{
+			name: "forwarded headers without proxy", host: "evil.example",
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			var got string
+			RequestOrigin(tt.trustProxy, public)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = GetOrigin(r.Context())
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("GetOrigin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
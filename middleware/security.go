@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"todo-api/config"
+)
+
+// SecurityHeaders добавляет защитные заголовки во все ответы. HSTS отправляется
+// только по HTTPS: напрямую (r.TLS) или через доверенный прокси (X-Forwarded-Proto).
+func SecurityHeaders(cfg config.SecurityConfig, trustProxy bool) func(http.Handler) http.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if cfg.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			}
+			if hsts != "" && IsHTTPS(r, trustProxy) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ContentSecurityPolicy задаёт CSP для ответов next. Нужен для загруженных
+// пользователями файлов: открытый напрямую SVG или HTML не должен исполнять скрипты.
+func ContentSecurityPolicy(policy string, next http.Handler) http.Handler {
+	if policy == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", policy)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsHTTPS сообщает, пришёл ли запрос по HTTPS
+func IsHTTPS(r *http.Request, trustProxy bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustProxy && r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry — запись журнала аудита. Changes — изменённые поля в виде
+// {"поле": {"from": старое, "to": новое}}; у входа — пустой объект.
+type AuditEntry struct {
+	ID         int64           `db:"id" json:"id"`
+	OccurredAt time.Time       `db:"occurred_at" json:"occurred_at"`
+	ActorID    *int            `db:"actor_id" json:"actor_id,omitempty"`
+	Action     string          `db:"action" json:"action" example:"todo.update"`
+	Outcome    string          `db:"outcome" json:"outcome" example:"success"`
+	TargetType string          `db:"target_type" json:"target_type" example:"todo"`
+	TargetID   *int            `db:"target_id" json:"target_id,omitempty"`
+	Changes    json.RawMessage `db:"changes" json:"changes" swaggertype:"object"`
+	IP         string          `db:"ip" json:"ip"`
+	RequestID  string          `db:"request_id" json:"request_id"`
+}
+
+// AuditFilter — условия выборки журнала; пустые поля не ограничивают
+type AuditFilter struct {
+	ActorID    *int
+	Action     string
+	Outcome    string
+	TargetType string
+	TargetID   *int
+	// Subject — записи, где пользователь действовал сам или над ним действовали
+	Subject *int
+	Since   *time.Time
+	Until   *time.Time
+	Limit   int
+	Offset  int
+}
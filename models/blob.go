@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type Blob struct {
+	Key       string    `db:"key" json:"key"`
+	Size      int64     `db:"size" json:"size"`
+	RefCount  int       `db:"ref_count" json:"ref_count"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// TodoFilter — условия выборки задач; задача подходит, если выполнены все
+// заданные условия. Пустой фильтр подходит для всех задач. Границы дат
+// абсолютные: «выполнено на этой неделе» — это диапазон конкретных дат.
+// Приоритетов и сроков у задач нет, поэтому и условий по ним нет.
+type TodoFilter struct {
+	// Done — только выполненные (true) или только невыполненные (false)
+	Done *bool `json:"done,omitempty"`
+	// HasPhoto — только задачи с фото (true) или без него (false)
+	HasPhoto *bool `json:"has_photo,omitempty"`
+	// Query — слова из названия, как в поиске: с морфологией и по префиксу
+	Query string `json:"query,omitempty" example:"купить"`
+	// CreatedAfter и CreatedBefore — задача создана в [after, before)
+	CreatedAfter  *time.Time `json:"created_after,omitempty" example:"2026-10-12T00:00:00Z"`
+	CreatedBefore *time.Time `json:"created_before,omitempty" example:"2026-10-19T00:00:00Z"`
+	// CompletedAfter и CompletedBefore — задача выполнена в [after, before);
+	// невыполненные задачи под них не подходят
+	CompletedAfter  *time.Time `json:"completed_after,omitempty" example:"2026-10-12T00:00:00Z"`
+	CompletedBefore *time.Time `json:"completed_before,omitempty" example:"2026-10-19T00:00:00Z"`
+}
+
+// SavedFilter — фильтр задач, сохранённый пользователем под именем
+type SavedFilter struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	Name      string     `json:"name" db:"name"`
+	Filter    TodoFilter `json:"filter" db:"-"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
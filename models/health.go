@@ -0,0 +1,15 @@
+package models
+
+// HealthCheck — результат проверки одной зависимости
+type HealthCheck struct {
+	Status    string `json:"status" example:"ok"`
+	LatencyMS int64  `json:"latency_ms" example:"3"`
+	Error     string `json:"error,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// HealthReport — сводка по всем проверкам готовности
+type HealthReport struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы агрегатов, к которым относятся события outbox
+const (
+	AggregateTodo = "todo"
+	AggregateUser = "user"
+)
+
+// OutboxEvent — доменное событие, записанное вместе с изменением. Payload —
+// состояние агрегата после изменения (для удаления — до него).
+type OutboxEvent struct {
+	ID            int64           `db:"id"`
+	AggregateType string          `db:"aggregate_type"`
+	AggregateID   int             `db:"aggregate_id"`
+	EventType     string          `db:"event_type"`
+	UserID        int             `db:"user_id"`
+	Payload       json.RawMessage `db:"payload"`
+	OccurredAt    time.Time       `db:"occurred_at"`
+	Attempts      int             `db:"attempts"`
+	LastError     *string         `db:"last_error"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// FieldError — ошибка в конкретном поле запроса.
+// Code стабилен и предназначен для клиентов, Message — для людей.
+type FieldError struct {
+	Field   string `json:"field" example:"title"`
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"title is required"`
+}
+
+// ProblemDetails — ответ об ошибке в формате RFC 7807 (application/problem+json)
+type ProblemDetails struct {
+	Type     string       `json:"type" example:"/problems/not-found"`
+	Title    string       `json:"title" example:"Not Found"`
+	Status   int          `json:"status" example:"404"`
+	Detail   string       `json:"detail,omitempty" example:"Todo not found"`
+	Instance string       `json:"instance,omitempty" example:"/api/todos/42"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
@@ -6,4 +6,13 @@ type GeneralResponse struct {
 	Data    any    `json:"data,omitempty"`
 	Errors  any    `json:"errors,omitempty"` //  []string или map[string]string
 	Meta    any    `json:"meta,omitempty"`   // Доп. инфо (пагинация и др.)
+	// UndoToken — токен для POST /undo в ответах на изменение задач
+	UndoToken string `json:"undo_token,omitempty"`
+}
+
+// PageMeta — meta постраничной выдачи. Limit == 0 — выдача без ограничения.
+type PageMeta struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
 }
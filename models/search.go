@@ -0,0 +1,11 @@
+package models
+
+// TodoSearchResult — задача, найденная полнотекстовым поиском
+type TodoSearchResult struct {
+	Todo
+	// Rank — релевантность: чем больше, тем выше в выдаче
+	Rank float64 `json:"rank" db:"rank"`
+	// Highlight — название с найденными словами в <mark>…</mark>; остальной
+	// текст экранирован, фрагмент можно вставлять как HTML
+	Highlight string `json:"highlight" db:"highlight"`
+}
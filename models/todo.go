@@ -1,9 +1,18 @@
 package models
 
+import "time"
+
 type Todo struct {
 	ID       int     `json:"id,omitempty" swaggerignore:"true"`
 	Title    string  `json:"title"`
 	Done     bool    `json:"done"`
 	UserID   int     `json:"user_id,omitempty" db:"user_id" swaggerignore:"true"`
 	PhotoURL *string `json:"photo_url,omitempty" db:"photo_url"`
+	// DeletedAt — момент переноса в корзину; у обычных задач пусто
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at" swaggerignore:"true"`
+	// CreatedAt, UpdatedAt и CompletedAt отдаются в списках задач.
+	// CompletedAt — когда задачу отметили выполненной; у невыполненных пусто.
+	CreatedAt   *time.Time `json:"created_at,omitempty" db:"created_at" swaggerignore:"true"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at" swaggerignore:"true"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at" swaggerignore:"true"`
 }
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Операции над задачами, которые можно отменить
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+)
+
+// TodoSnapshot — поля задачи, которые меняет и возвращает отмена
+type TodoSnapshot struct {
+	Title    string  `json:"title"`
+	Done     bool    `json:"done"`
+	PhotoURL *string `json:"photo_url,omitempty"`
+}
+
+// SnapshotOf снимает изменяемые поля задачи
+func SnapshotOf(todo Todo) TodoSnapshot {
+	return TodoSnapshot{Title: todo.Title, Done: todo.Done, PhotoURL: todo.PhotoURL}
+}
+
+// Matches сообщает, что поля задачи совпадают со снимком
+func (s TodoSnapshot) Matches(todo Todo) bool {
+	return s.Title == todo.Title && s.Done == todo.Done && sameURL(s.PhotoURL, todo.PhotoURL)
+}
+
+func sameURL(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// TodoOperation — запись журнала отмены. Before пуст у создания задачи.
+type TodoOperation struct {
+	ID     int64         `db:"id"`
+	Token  string        `db:"token"`
+	UserID int           `db:"user_id"`
+	TodoID int           `db:"todo_id"`
+	Op     string        `db:"op"`
+	Before *TodoSnapshot `db:"-"`
+	After  TodoSnapshot  `db:"-"`
+	// HoldsPhoto — запись держит ссылку на фото из Before
+	HoldsPhoto bool       `db:"holds_photo"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UndoneAt   *time.Time `db:"undone_at"`
+}
+
+// PhotoChanged сообщает, что операция заменила или добавила фото задачи
+func (op TodoOperation) PhotoChanged() bool {
+	return op.Before != nil && !sameURL(op.Before.PhotoURL, op.After.PhotoURL)
+}
@@ -1,7 +1,25 @@
 package models
 
+import "time"
+
 type User struct {
 	ID           int    `db:"id" json:"id"`
 	Username     string `db:"username" json:"username"`
 	PasswordHash string `db:"password_hash" json:"-"`
+	Role         string `db:"role" json:"role" example:"user"`
+
+	// Состояние защиты от перебора паролей
+	FailedLoginAttempts int        `db:"failed_login_attempts" json:"-"`
+	LockedUntil         *time.Time `db:"locked_until" json:"-"`
+}
+
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Locked сообщает, заблокирован ли вход на момент now
+func (u User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook — адрес, на который отправляются события пользователя.
+// Secret показывается только при создании.
+type Webhook struct {
+	ID        int       `db:"id" json:"id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	URL       string    `db:"url" json:"url" example:"https://example.com/hooks/todo"`
+	Events    []string  `db:"events" json:"events" example:"todo.created,todo.completed"`
+	Secret    string    `db:"secret" json:"-"`
+	Active    bool      `db:"active" json:"active"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery — одно событие в очереди доставки
+type WebhookDelivery struct {
+	ID            int64           `db:"id" json:"id"`
+	WebhookID     *int            `db:"webhook_id" json:"webhook_id,omitempty"`
+	Event         string          `db:"event" json:"event"`
+	Payload       json.RawMessage `db:"payload" json:"payload" swaggertype:"object"`
+	Status        string          `db:"status" json:"status" example:"pending"`
+	Attempts      int             `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+	DeliveredAt   *time.Time      `db:"delivered_at" json:"delivered_at,omitempty"`
+
+	// Log — журнал попыток; заполняется только при запросе одной доставки
+	Log []WebhookAttempt `db:"-" json:"attempt_log,omitempty"`
+}
+
+// DueDelivery — доставка, взятая в работу, вместе с адресом и секретом вебхука
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// WebhookAttempt — запись журнала попыток доставки
+type WebhookAttempt struct {
+	Attempt     int       `db:"attempt" json:"attempt"`
+	StatusCode  *int      `db:"status_code" json:"status_code,omitempty"`
+	Error       *string   `db:"error" json:"error,omitempty"`
+	DurationMS  int       `db:"duration_ms" json:"duration_ms"`
+	AttemptedAt time.Time `db:"attempted_at" json:"attempted_at"`
+}
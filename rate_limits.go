@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/config"
+	"todo-api/db"
+	"todo-api/handlers"
+	"todo-api/ratelimit"
+)
+
+// rateLimitCleanupInterval — как часто выбрасывать наполнившиеся вёдра
+const rateLimitCleanupInterval = 10 * time.Minute
+
+// rateLimits — лимитеры приложения, собранные по конфигурации
+type rateLimits struct {
+	guard handlers.LoginProtection
+	// api — общий лимит на пользователя для /api; nil — выключен
+	api ratelimit.Limiter
+	// cleanup периодически чистит хранилище вёдер до отмены ctx
+	cleanup func(ctx context.Context)
+}
+
+func newRateLimits(cfg config.RateLimitConfig, trustProxy bool, pg *db.PostgresStore) (rateLimits, error) {
+	var (
+		memory  []*ratelimit.MemoryLimiter
+		longest time.Duration
+	)
+
+	// newLimiter возвращает nil для выключенного (limit <= 0) лимита
+	var newLimiter func(name string, limit int, per time.Duration) ratelimit.Limiter
+	switch cfg.Backend {
+	case "memory":
+		newLimiter = func(_ string, limit int, per time.Duration) ratelimit.Limiter {
+			if limit <= 0 || per <= 0 {
+				return nil
+			}
+			l := ratelimit.NewMemoryLimiter(ratelimit.Rate{Limit: limit, Per: per})
+			memory = append(memory, l)
+			return l
+		}
+	case "postgres":
+		newLimiter = func(name string, limit int, per time.Duration) ratelimit.Limiter {
+			if limit <= 0 || per <= 0 {
+				return nil
+			}
+			longest = max(longest, per)
+			return ratelimit.NewStoreLimiter(pg, name, ratelimit.Rate{Limit: limit, Per: per})
+		}
+	default:
+		return rateLimits{}, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q (want memory or postgres)", cfg.Backend)
+	}
+
+	limits := rateLimits{
+		guard: handlers.LoginProtection{
+			LoginByIP:       newLimiter("login_ip", cfg.LoginPerIP, cfg.LoginWindow),
+			LoginByUsername: newLimiter("login_user", cfg.LoginPerUsername, cfg.LoginWindow),
+			RegisterByIP:    newLimiter("register_ip", cfg.RegisterPerIP, cfg.RegisterWindow),
+			Lockout: auth.Lockout{
+				Threshold: cfg.LockoutThreshold,
+				Delay:     cfg.LockoutDelay,
+				MaxDelay:  cfg.LockoutMaxDelay,
+			},
+			TrustProxy: trustProxy,
+		},
+		api: newLimiter("api", cfg.APIPerUser, cfg.APIWindow),
+	}
+
+	limits.cleanup = func(ctx context.Context) {
+		ticker := time.NewTicker(rateLimitCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			for _, l := range memory {
+				l.Cleanup()
+			}
+			if longest > 0 {
+				if _, err := pg.PruneRateLimits(ctx, longest); err != nil && ctx.Err() == nil {
+					slog.Error("failed to prune rate limit buckets", "error", err)
+				}
+			}
+		}
+	}
+	return limits, nil
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter хранит вёдра в памяти процесса. Подходит для одного инстанса;
+// при нескольких инстансах лимит фактически умножается на их число.
+type MemoryLimiter struct {
+	rate Rate
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{
+		rate:    rate,
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limit := float64(l.rate.Limit)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(limit, b.tokens+now.Sub(b.updated).Seconds()*l.rate.PerSecond())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(l.rate, allowed, b.tokens), nil
+}
+
+// Cleanup удаляет вёдра, которые успели наполниться полностью: они ничем
+// не отличаются от отсутствующих, а память занимают
+func (l *MemoryLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.rate.Per {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterBuckets(t *testing.T) {
+	// 3 запроса за 3 секунды: ведро на 3 токена, один токен в секунду
+	rate := Rate{Limit: 3, Per: 3 * time.Second}
+
+	type step struct {
+		advance    time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst then refuse",
+			steps: []step{
+				{key: "a", allowed: true, remaining: 2},
+				{key: "a", allowed: true, remaining: 1},
+				{key: "a", allowed: true, remaining: 0},
+				{key: "a", allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "refill over time",
+			steps: []step{
+				{key: "a", allowed: true, remaining: 2},
+				{key: "a", allowed: true, remaining: 1},
+				{key: "a", allowed: true, remaining: 0},
+				{advance: 500 * time.Millisecond, key: "a", allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+				{advance: 500 * time.Millisecond, key: "a", allowed: true, remaining: 0},
+				{advance: 2 * time.Second, key: "a", allowed: true, remaining: 1},
+			},
+		},
+		{
+			name: "refill capped at limit",
+			steps: []step{
+				{key: "a", allowed: true, remaining: 2},
+				{advance: time.Hour, key: "a", allowed: true, remaining: 2},
+			},
+		},
+		{
+			name: "keys are independent",
+			steps: []step{
+				{key: "a", allowed: true, remaining: 2},
+				{key: "a", allowed: true, remaining: 1},
+				{key: "a", allowed: true, remaining: 0},
+				{key: "b", allowed: true, remaining: 2},
+				{key: "a", allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			l := NewMemoryLimiter(rate)
+			l.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				res, err := l.Allow(context.Background(), s.key)
+				if err != nil {
+					t.Fatalf("step %d: Allow() error = %v", i, err)
+				}
+				if res.Allowed != s.allowed || res.Remaining != s.remaining || res.RetryAfter != s.retryAfter {
+					t.Errorf("step %d: allowed/remaining/retry_after = %v/%d/%v, want %v/%d/%v",
+						i, res.Allowed, res.Remaining, res.RetryAfter, s.allowed, s.remaining, s.retryAfter)
+				}
+				if res.Limit != rate.Limit {
+					t.Errorf("step %d: Limit = %d, want %d", i, res.Limit, rate.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterCleanup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryLimiter(Rate{Limit: 2, Per: time.Minute})
+	l.now = func() time.Time { return now }
+
+	l.Allow(context.Background(), "old")
+	now = now.Add(30 * time.Second)
+	l.Allow(context.Background(), "recent")
+	now = now.Add(30 * time.Second)
+	l.Cleanup()
+
+	if _, ok := l.buckets["old"]; ok {
+		t.Error("full bucket was not removed")
+	}
+	if _, ok := l.buckets["recent"]; !ok {
+		t.Error("bucket that is still refilling was removed")
+	}
+}
+
+func TestResultReset(t *testing.T) {
+	rate := Rate{Limit: 10, Per: 10 * time.Second}
+	tests := []struct {
+		tokens float64
+		want   time.Duration
+	}{
+		{10, 0},
+		{9, time.Second},
+		{0, 10 * time.Second},
+		{2.5, 7500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := result(rate, true, tt.tokens).Reset; got != tt.want {
+			t.Errorf("Reset with %v tokens = %v, want %v", tt.tokens, got, tt.want)
+		}
+	}
+}
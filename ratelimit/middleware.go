@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-api/auth"
+	"todo-api/logging"
+	"todo-api/models"
+)
+
+// Middleware ограничивает частоту запросов к /api: ключ — пользователь из JWT,
+// для анонимных запросов — IP. В каждый ответ добавляются заголовки RateLimit-*.
+// Если лимитер недоступен (например, упала БД), запрос пропускается.
+func Middleware(limiter Limiter, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := "ip:" + ClientIP(r, trustProxy)
+			if userID, err := auth.ExtractUserIDFromRequest(r); err == nil {
+				key = "user:" + strconv.Itoa(userID)
+			}
+
+			res, err := limiter.Allow(r.Context(), key)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			SetHeaders(w, res)
+			if !res.Allowed {
+				WriteTooManyRequests(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteTooManyRequests отвечает 429 в общем формате API
+func WriteTooManyRequests(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(models.GeneralResponse{
+		Status:  "error",
+		Message: "Too many requests",
+	})
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
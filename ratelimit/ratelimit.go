@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Result — решение лимитера по одному запросу
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — через сколько ведро наполнится полностью
+	Reset time.Duration
+	// RetryAfter — через сколько появится следующий токен (для отказов)
+	RetryAfter time.Duration
+}
+
+// Limiter — token bucket: Burst токенов, пополнение Rate токенов в секунду.
+// Каждый вызов Allow тратит один токен из ведра с ключом key.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Rate задаёт лимит вида «Limit запросов за Per»
+type Rate struct {
+	Limit int
+	Per   time.Duration
+}
+
+// PerSecond — скорость пополнения ведра
+func (r Rate) PerSecond() float64 {
+	if r.Per <= 0 {
+		return 0
+	}
+	return float64(r.Limit) / r.Per.Seconds()
+}
+
+// result собирает Result по числу оставшихся токенов
+func result(rate Rate, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     rate.Limit,
+		Remaining: max(0, int(math.Floor(tokens))),
+	}
+	if perSecond := rate.PerSecond(); perSecond > 0 {
+		res.Reset = secondsToDuration((float64(rate.Limit) - tokens) / perSecond)
+		if !allowed {
+			res.RetryAfter = secondsToDuration((1 - tokens) / perSecond)
+		}
+	}
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// SetHeaders выставляет заголовки RateLimit-* (draft-ietf-httpapi-ratelimit-headers)
+// и Retry-After для отказов
+func SetHeaders(w http.ResponseWriter, res Result) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", itoa(res.Limit))
+	h.Set("RateLimit-Remaining", itoa(res.Remaining))
+	h.Set("RateLimit-Reset", itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		h.Set("Retry-After", itoa(max(1, ceilSeconds(res.RetryAfter))))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP возвращает IP клиента. Заголовку X-Forwarded-For можно верить,
+// только если сервис стоит за своим прокси (trustProxy).
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
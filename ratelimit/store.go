@@ -0,0 +1,29 @@
+package ratelimit
+
+import "context"
+
+// BucketStore — общее для всех инстансов хранилище вёдер (например, Postgres).
+// TakeToken атомарно пополняет ведро key и пытается взять из него токен.
+type BucketStore interface {
+	TakeToken(ctx context.Context, key string, limit int, perSecond float64) (allowed bool, tokens float64, err error)
+}
+
+// StoreLimiter — Limiter поверх BucketStore
+type StoreLimiter struct {
+	rate   Rate
+	store  BucketStore
+	prefix string
+}
+
+// NewStoreLimiter создаёт лимитер; prefix отделяет вёдра разных лимитеров в общей таблице
+func NewStoreLimiter(store BucketStore, prefix string, rate Rate) *StoreLimiter {
+	return &StoreLimiter{rate: rate, store: store, prefix: prefix}
+}
+
+func (l *StoreLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	allowed, tokens, err := l.store.TakeToken(ctx, l.prefix+":"+key, l.rate.Limit, l.rate.PerSecond())
+	if err != nil {
+		return Result{}, err
+	}
+	return result(l.rate, allowed, tokens), nil
+}
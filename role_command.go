@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"todo-api/config"
+	"todo-api/db"
+	"todo-api/models"
+)
+
+// runSetRole назначает роль пользователю из CLI: todo-api set-role <username> <user|admin>.
+// Через API роль не выдаётся — первого администратора иначе не назначить.
+func runSetRole(cfg config.Config, args []string) int {
+	if len(args) != 2 || (args[1] != models.RoleUser && args[1] != models.RoleAdmin) {
+		fmt.Fprintln(os.Stderr, "usage: todo-api set-role <username> <user|admin>")
+		return 2
+	}
+	username, role := args[0], args[1]
+
+	ctx := context.Background()
+	store, err := db.NewPostgresStore(ctx, cfg.DB)
+	if err != nil {
+		slog.Error("failed to connect to DB", "error", err)
+		return 1
+	}
+	defer store.Close()
+
+	if err := store.Migrate(ctx); err != nil {
+		slog.Error("failed to apply migrations", "error", err)
+		return 1
+	}
+
+	user, err := store.GetByUsername(ctx, username)
+	if err != nil {
+		slog.Error("failed to find user", "username", username, "error", err)
+		return 1
+	}
+	if _, err := store.SetUserRole(ctx, user.ID, role); err != nil {
+		slog.Error("failed to set role", "username", username, "error", err)
+		return 1
+	}
+	slog.Info("role updated", "username", username, "user_id", user.ID, "role", role)
+	return 0
+}
@@ -0,0 +1,14 @@
+package store
+
+import (
+	"context"
+
+	"todo-api/models"
+)
+
+type AuditStore interface {
+	// RecordAudit добавляет запись; записи журнала не изменяются и не удаляются
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+	// GetAuditEntries возвращает записи по filter, новые первыми
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Все методы хранилищ принимают ctx запроса и обязаны прерывать работу при его
+// отмене. Обращения в рамках одного HTTP-запроса делят общий дедлайн
+// (config.DB.RequestTimeout), а каждое отдельное вдобавок ограничено
+// config.DB.QueryTimeout, чтобы медленный запрос не держал соединение дольше положенного.
+
+type deadlineKey struct{}
+
+// WithDeadline задаёт общий дедлайн всех обращений к хранилищу через ctx.
+// Сам ctx не ограничивается: обработчик может работать и дальше, но без БД.
+// Более ранний дедлайн, уже заданный в ctx, сохраняется.
+func WithDeadline(ctx context.Context, deadline time.Time) context.Context {
+	if d, ok := ctx.Value(deadlineKey{}).(time.Time); ok && d.Before(deadline) {
+		return ctx
+	}
+	return context.WithValue(ctx, deadlineKey{}, deadline)
+}
+
+// WithQueryTimeout ограничивает ctx таймаутом одного обращения к хранилищу и
+// общим дедлайном из WithDeadline — тем, что наступит раньше. timeout <= 0
+// оставляет только общий дедлайн. Более ранний дедлайн самого ctx сохраняется.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Value(deadlineKey{}).(time.Time)
+	if timeout > 0 {
+		if limit := time.Now().Add(timeout); !ok || limit.Before(deadline) {
+			deadline, ok = limit, true
+		}
+	}
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithQueryTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		// request — общий дедлайн относительно текущего момента, 0 — не задан
+		request time.Duration
+		timeout time.Duration
+		// want — ожидаемый дедлайн относительно текущего момента, 0 — без дедлайна
+		want time.Duration
+	}{
+		{name: "no limits"},
+		{name: "query timeout only", timeout: time.Second, want: time.Second},
+		{name: "request deadline only", request: time.Minute, want: time.Minute},
+		{name: "query timeout caps request", request: time.Minute, timeout: time.Second, want: time.Second},
+		{name: "request deadline caps query", request: time.Second, timeout: time.Minute, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			ctx := context.Background()
+			if tt.request > 0 {
+				ctx = WithDeadline(ctx, now.Add(tt.request))
+			}
+			ctx, cancel := WithQueryTimeout(ctx, tt.timeout)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if ok != (tt.want > 0) {
+				t.Fatalf("Deadline() ok = %v, want %v", ok, tt.want > 0)
+			}
+			if ok && deadline.Sub(now.Add(tt.want)).Abs() > 100*time.Millisecond {
+				t.Errorf("deadline in %v, want %v", deadline.Sub(now), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDeadlineKeepsEarlier(t *testing.T) {
+	now := time.Now()
+	ctx := WithDeadline(context.Background(), now.Add(time.Second))
+	ctx = WithDeadline(ctx, now.Add(time.Minute))
+
+	ctx, cancel := WithQueryTimeout(ctx, 0)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(now.Add(time.Second)) {
+		t.Errorf("deadline = %v, want the earlier one %v", deadline, now.Add(time.Second))
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"todo-api/models"
+)
+
+// Базовые ошибки, с которыми удобно сравнивать через errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	// ErrCanceled — запрос прерван по отмене или дедлайну ctx
+	ErrCanceled = errors.New("query canceled")
+)
+
+// NotFoundError — запрошенная сущность не существует
+type NotFoundError struct {
+	Entity string
+	ID     any
+}
+
+func (e *NotFoundError) Error() string {
+	if e.ID == nil {
+		return fmt.Sprintf("%s not found", e.Entity)
+	}
+	return fmt.Sprintf("%s %v not found", e.Entity, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ConflictError — нарушено ограничение уникальности или ссылочной целостности
+type ConflictError struct {
+	Entity string
+	Field  string
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s conflict: %s", e.Entity, e.Reason)
+	}
+	return fmt.Sprintf("%s conflict on %s: %s", e.Entity, e.Field, e.Reason)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// ValidationError — входные данные не прошли проверку
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return ErrValidation.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, e.Fields[0].Message)
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// NewValidationError создаёт ошибку валидации для одного поля
+func NewValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []models.FieldError{{Field: field, Code: code, Message: message}}}
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"context"
+
+	"todo-api/models"
+)
+
+// FilterStore хранит сохранённые фильтры задач
+type FilterStore interface {
+	CreateFilter(ctx context.Context, filter models.SavedFilter) (models.SavedFilter, error)
+	GetFilters(ctx context.Context, userID int) ([]models.SavedFilter, error)
+	GetFilterByID(ctx context.Context, id int) (models.SavedFilter, error)
+	// UpdateFilter меняет имя и условия фильтра
+	UpdateFilter(ctx context.Context, id int, filter models.SavedFilter) (models.SavedFilter, error)
+	DeleteFilter(ctx context.Context, id int) error
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+)
+
+// OutboxStore — очередь доменных событий для relay. Сами события пишут
+// методы изменения TodoStore и UserStore.
+type OutboxStore interface {
+	// ClaimOutboxEvents берёт в работу до limit событий — не больше одного,
+	// самого раннего необработанного, на агрегат — и откладывает их на lease.
+	// Следующее событие агрегата не выдаётся, пока не обработано предыдущее.
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkOutboxProcessed(ctx context.Context, id int64) error
+	// RetryOutboxEvent откладывает событие до retryAt, запоминая ошибку
+	RetryOutboxEvent(ctx context.Context, id int64, lastError string, retryAt time.Time) error
+	// PruneOutbox удаляет события, обработанные раньше olderThan назад
+	PruneOutbox(ctx context.Context, olderThan time.Duration) (int64, error)
+}
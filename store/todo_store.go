@@ -1,11 +1,36 @@
 package store
 
-import "todo-api/models"
+import (
+	"context"
+	"time"
 
+	"todo-api/models"
+)
+
+// TodoStore работает с задачами вне корзины, кроме методов корзины ниже
 type TodoStore interface {
-	GetTodos(userID int) ([]models.Todo, error)
-	CreateTodo(models.Todo) (models.Todo, error)
-	UpdateTodo(id int, updated models.Todo) (models.Todo, error)
-	DeleteTodo(id int) error
-	GetTodoByID(id int) (models.Todo, error)
+	GetTodos(ctx context.Context, userID int) ([]models.Todo, error)
+	// FilterTodos возвращает страницу задач пользователя, подходящих под
+	// filter, в порядке id; limit == 0 — без ограничения
+	FilterTodos(ctx context.Context, userID int, filter models.TodoFilter, limit, offset int) ([]models.Todo, error)
+	CreateTodo(ctx context.Context, todo models.Todo) (models.Todo, error)
+	UpdateTodo(ctx context.Context, id int, updated models.Todo) (models.Todo, error)
+	// DeleteTodo переносит задачу в корзину
+	DeleteTodo(ctx context.Context, id int) error
+	GetTodoByID(ctx context.Context, id int) (models.Todo, error)
+	// LockTodo загружает задачу, в том числе из корзины (DeletedAt), и
+	// блокирует её строку до конца транзакции
+	LockTodo(ctx context.Context, id int) (models.Todo, error)
+	// SearchTodos ищет задачи пользователя по словам query (каждое — по
+	// префиксу), самые релевантные первыми
+	SearchTodos(ctx context.Context, userID int, query string, limit, offset int) ([]models.TodoSearchResult, error)
+
+	// GetTrash возвращает корзину пользователя, недавно удалённые первыми
+	GetTrash(ctx context.Context, userID int) ([]models.Todo, error)
+	GetDeletedTodo(ctx context.Context, id int) (models.Todo, error)
+	RestoreTodo(ctx context.Context, id int) (models.Todo, error)
+	// PurgeTodo окончательно удаляет задачу из корзины
+	PurgeTodo(ctx context.Context, id int) error
+	// GetExpiredTrash возвращает до limit задач, лежащих в корзине дольше olderThan
+	GetExpiredTrash(ctx context.Context, olderThan time.Duration, limit int) ([]models.Todo, error)
 }
@@ -0,0 +1,20 @@
+package store
+
+import "context"
+
+// Tx — хранилища, все операции которых выполняются в одной транзакции
+type Tx interface {
+	TodoStore
+	UserStore
+	UploadStore
+	WebhookStore
+	AuditStore
+	UndoStore
+}
+
+// Transactor выполняет fn в транзакции: коммит, если fn вернула nil, иначе откат.
+// При конфликте сериализации fn может быть вызвана повторно, поэтому
+// побочные эффекты вне БД в ней делать нельзя.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(Tx) error) error
+}
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"context"
+
+	"todo-api/models"
+)
+
+// UndoStore — журнал операций над задачами для отмены
+type UndoStore interface {
+	RecordTodoOperation(ctx context.Context, op models.TodoOperation) (models.TodoOperation, error)
+	GetTodoOperation(ctx context.Context, token string) (models.TodoOperation, error)
+	// MarkTodoOperationUndone отмечает операцию отменённой и снимает с неё
+	// ссылку на фото; повторная отмена — ConflictError
+	MarkTodoOperationUndone(ctx context.Context, id int64) error
+	// TakeExpiredTodoOperations удаляет до limit записей с истёкшим окном
+	// отмены и возвращает их, чтобы отпустить удерживаемые фото
+	TakeExpiredTodoOperations(ctx context.Context, limit int) ([]models.TodoOperation, error)
+	// DeleteUserTodoOperations удаляет журнал пользователя и возвращает удалённое
+	DeleteUserTodoOperations(ctx context.Context, userID int) ([]models.TodoOperation, error)
+}
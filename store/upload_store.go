@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+
+	"todo-api/models"
+)
+
+// UploadStore ведёт учёт ссылок на загруженные файлы
+type UploadStore interface {
+	GetPhotoURLs(ctx context.Context) ([]string, error)
+	GetBlobKeys(ctx context.Context) ([]string, error)
+	// AcquireBlob добавляет ссылку на файл, создавая запись при первой загрузке
+	AcquireBlob(ctx context.Context, key string, size int64) (models.Blob, error)
+	// ReleaseBlob снимает ссылку. Запись без ссылок остаётся: файл удаляет
+	// только сборщик, через ClaimUnreferencedBlob
+	ReleaseBlob(ctx context.Context, key string) error
+	// ClaimUnreferencedBlob блокирует запись о файле до конца транзакции и,
+	// если ссылок на него нет, удаляет её. true — файл можно удалять, пока
+	// транзакция открыта: AcquireBlob на этот ключ будет ждать её коммита.
+	ClaimUnreferencedBlob(ctx context.Context, key string) (bool, error)
+}
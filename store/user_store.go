@@ -1,12 +1,27 @@
 package store
 
-import "todo-api/models"
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+)
 
 type UserStore interface {
-	GetUsers() ([]models.User, error)
-	CreateUser(models.User) (models.User, error)
-	UpdateUser(id int, updated models.User) (models.User, error)
-	DeleteUser(id int) error
-	GetUserByID(id int) (models.User, error)
-	GetByUsername(username string) (models.User, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	UpdateUsername(ctx context.Context, id int, username string) (models.User, error)
+	UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error
+	DeleteUser(ctx context.Context, id int) error
+	GetUserByID(ctx context.Context, id int) (models.User, error)
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+	// SetUserRole назначает пользователю роль models.RoleUser или models.RoleAdmin
+	SetUserRole(ctx context.Context, id int, role string) (models.User, error)
+
+	// RecordLoginFailure увеличивает счётчик неудачных входов подряд и возвращает его
+	RecordLoginFailure(ctx context.Context, id int) (int, error)
+	// LockUser запрещает вход до момента until
+	LockUser(ctx context.Context, id int, until time.Time) error
+	// ResetLoginFailures сбрасывает счётчик и блокировку после успешного входа
+	ResetLoginFailures(ctx context.Context, id int) error
 }
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+)
+
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error)
+	GetWebhooks(ctx context.Context, userID int) ([]models.Webhook, error)
+	GetWebhookByID(ctx context.Context, id int) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+
+	// EnqueueWebhookDeliveries ставит событие в очередь каждому активному
+	// вебхуку пользователя, подписанному на event
+	EnqueueWebhookDeliveries(ctx context.Context, userID int, event string, payload []byte) (int64, error)
+	// ClaimWebhookDeliveries берёт в работу до limit наступивших доставок и
+	// откладывает их на lease: если инстанс упадёт, доставку возьмёт другой
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error)
+	// RecordWebhookAttempt пишет попытку в журнал и переводит доставку в status
+	RecordWebhookAttempt(ctx context.Context, id int64, attempt models.WebhookAttempt, status string, nextAttemptAt time.Time) error
+
+	GetWebhookDeliveries(ctx context.Context, webhookID, limit, offset int) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error)
+	// RedeliverWebhook возвращает доставку (в том числе dead) в очередь с новым счётчиком попыток
+	RedeliverWebhook(ctx context.Context, id int64) (models.WebhookDelivery, error)
+}
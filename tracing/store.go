@@ -0,0 +1,417 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+	"todo-api/store"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Декораторы хранилищ: по спану на каждый вызов метода.
+// Сами SQL-запросы становятся дочерними спанами уровнем ниже, в пакете db.
+
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "store."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("store.method", method)),
+	)
+}
+
+// endSpan вызывается через defer; err читается уже после возврата из метода
+func endSpan(span trace.Span, err *error) {
+	if *err != nil && !errors.Is(*err, store.ErrNotFound) {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+type TodoStore struct {
+	Next store.TodoStore
+}
+
+func (s TodoStore) GetTodos(ctx context.Context, userID int) (_ []models.Todo, err error) {
+	ctx, span := startSpan(ctx, "GetTodos")
+	defer endSpan(span, &err)
+	return s.Next.GetTodos(ctx, userID)
+}
+
+func (s TodoStore) CreateTodo(ctx context.Context, todo models.Todo) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "CreateTodo")
+	defer endSpan(span, &err)
+	return s.Next.CreateTodo(ctx, todo)
+}
+
+func (s TodoStore) UpdateTodo(ctx context.Context, id int, updated models.Todo) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "UpdateTodo")
+	defer endSpan(span, &err)
+	return s.Next.UpdateTodo(ctx, id, updated)
+}
+
+func (s TodoStore) DeleteTodo(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteTodo")
+	defer endSpan(span, &err)
+	return s.Next.DeleteTodo(ctx, id)
+}
+
+func (s TodoStore) GetTodoByID(ctx context.Context, id int) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "GetTodoByID")
+	defer endSpan(span, &err)
+	return s.Next.GetTodoByID(ctx, id)
+}
+
+func (s TodoStore) FilterTodos(ctx context.Context, userID int, filter models.TodoFilter, limit, offset int) (_ []models.Todo, err error) {
+	ctx, span := startSpan(ctx, "FilterTodos")
+	defer endSpan(span, &err)
+	return s.Next.FilterTodos(ctx, userID, filter, limit, offset)
+}
+
+func (s TodoStore) SearchTodos(ctx context.Context, userID int, query string, limit, offset int) (_ []models.TodoSearchResult, err error) {
+	ctx, span := startSpan(ctx, "SearchTodos")
+	defer endSpan(span, &err)
+	return s.Next.SearchTodos(ctx, userID, query, limit, offset)
+}
+
+func (s TodoStore) LockTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "LockTodo")
+	defer endSpan(span, &err)
+	return s.Next.LockTodo(ctx, id)
+}
+
+func (s TodoStore) GetTrash(ctx context.Context, userID int) (_ []models.Todo, err error) {
+	ctx, span := startSpan(ctx, "GetTrash")
+	defer endSpan(span, &err)
+	return s.Next.GetTrash(ctx, userID)
+}
+
+func (s TodoStore) GetDeletedTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "GetDeletedTodo")
+	defer endSpan(span, &err)
+	return s.Next.GetDeletedTodo(ctx, id)
+}
+
+func (s TodoStore) RestoreTodo(ctx context.Context, id int) (_ models.Todo, err error) {
+	ctx, span := startSpan(ctx, "RestoreTodo")
+	defer endSpan(span, &err)
+	return s.Next.RestoreTodo(ctx, id)
+}
+
+func (s TodoStore) PurgeTodo(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "PurgeTodo")
+	defer endSpan(span, &err)
+	return s.Next.PurgeTodo(ctx, id)
+}
+
+func (s TodoStore) GetExpiredTrash(ctx context.Context, olderThan time.Duration, limit int) (_ []models.Todo, err error) {
+	ctx, span := startSpan(ctx, "GetExpiredTrash")
+	defer endSpan(span, &err)
+	return s.Next.GetExpiredTrash(ctx, olderThan, limit)
+}
+
+type UserStore struct {
+	Next store.UserStore
+}
+
+func (s UserStore) GetUsers(ctx context.Context) (_ []models.User, err error) {
+	ctx, span := startSpan(ctx, "GetUsers")
+	defer endSpan(span, &err)
+	return s.Next.GetUsers(ctx)
+}
+
+func (s UserStore) CreateUser(ctx context.Context, user models.User) (_ models.User, err error) {
+	ctx, span := startSpan(ctx, "CreateUser")
+	defer endSpan(span, &err)
+	return s.Next.CreateUser(ctx, user)
+}
+
+func (s UserStore) UpdateUsername(ctx context.Context, id int, username string) (_ models.User, err error) {
+	ctx, span := startSpan(ctx, "UpdateUsername")
+	defer endSpan(span, &err)
+	return s.Next.UpdateUsername(ctx, id, username)
+}
+
+func (s UserStore) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) (err error) {
+	ctx, span := startSpan(ctx, "UpdatePasswordHash")
+	defer endSpan(span, &err)
+	return s.Next.UpdatePasswordHash(ctx, id, passwordHash)
+}
+
+func (s UserStore) DeleteUser(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteUser")
+	defer endSpan(span, &err)
+	return s.Next.DeleteUser(ctx, id)
+}
+
+func (s UserStore) GetUserByID(ctx context.Context, id int) (_ models.User, err error) {
+	ctx, span := startSpan(ctx, "GetUserByID")
+	defer endSpan(span, &err)
+	return s.Next.GetUserByID(ctx, id)
+}
+
+func (s UserStore) GetByUsername(ctx context.Context, username string) (_ models.User, err error) {
+	ctx, span := startSpan(ctx, "GetByUsername")
+	defer endSpan(span, &err)
+	return s.Next.GetByUsername(ctx, username)
+}
+
+func (s UserStore) SetUserRole(ctx context.Context, id int, role string) (_ models.User, err error) {
+	ctx, span := startSpan(ctx, "SetUserRole")
+	defer endSpan(span, &err)
+	return s.Next.SetUserRole(ctx, id, role)
+}
+
+func (s UserStore) RecordLoginFailure(ctx context.Context, id int) (_ int, err error) {
+	ctx, span := startSpan(ctx, "RecordLoginFailure")
+	defer endSpan(span, &err)
+	return s.Next.RecordLoginFailure(ctx, id)
+}
+
+func (s UserStore) LockUser(ctx context.Context, id int, until time.Time) (err error) {
+	ctx, span := startSpan(ctx, "LockUser")
+	defer endSpan(span, &err)
+	return s.Next.LockUser(ctx, id, until)
+}
+
+func (s UserStore) ResetLoginFailures(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "ResetLoginFailures")
+	defer endSpan(span, &err)
+	return s.Next.ResetLoginFailures(ctx, id)
+}
+
+type UploadStore struct {
+	Next store.UploadStore
+}
+
+func (s UploadStore) GetPhotoURLs(ctx context.Context) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "GetPhotoURLs")
+	defer endSpan(span, &err)
+	return s.Next.GetPhotoURLs(ctx)
+}
+
+func (s UploadStore) GetBlobKeys(ctx context.Context) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "GetBlobKeys")
+	defer endSpan(span, &err)
+	return s.Next.GetBlobKeys(ctx)
+}
+
+func (s UploadStore) AcquireBlob(ctx context.Context, key string, size int64) (_ models.Blob, err error) {
+	ctx, span := startSpan(ctx, "AcquireBlob")
+	defer endSpan(span, &err)
+	return s.Next.AcquireBlob(ctx, key, size)
+}
+
+func (s UploadStore) ReleaseBlob(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "ReleaseBlob")
+	defer endSpan(span, &err)
+	return s.Next.ReleaseBlob(ctx, key)
+}
+
+func (s UploadStore) ClaimUnreferencedBlob(ctx context.Context, key string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ClaimUnreferencedBlob")
+	defer endSpan(span, &err)
+	return s.Next.ClaimUnreferencedBlob(ctx, key)
+}
+
+type WebhookStore struct {
+	Next store.WebhookStore
+}
+
+func (s WebhookStore) CreateWebhook(ctx context.Context, hook models.Webhook) (_ models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "CreateWebhook")
+	defer endSpan(span, &err)
+	return s.Next.CreateWebhook(ctx, hook)
+}
+
+func (s WebhookStore) GetWebhooks(ctx context.Context, userID int) (_ []models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "GetWebhooks")
+	defer endSpan(span, &err)
+	return s.Next.GetWebhooks(ctx, userID)
+}
+
+func (s WebhookStore) GetWebhookByID(ctx context.Context, id int) (_ models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "GetWebhookByID")
+	defer endSpan(span, &err)
+	return s.Next.GetWebhookByID(ctx, id)
+}
+
+func (s WebhookStore) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteWebhook")
+	defer endSpan(span, &err)
+	return s.Next.DeleteWebhook(ctx, id)
+}
+
+func (s WebhookStore) EnqueueWebhookDeliveries(ctx context.Context, userID int, event string, payload []byte) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "EnqueueWebhookDeliveries")
+	defer endSpan(span, &err)
+	return s.Next.EnqueueWebhookDeliveries(ctx, userID, event, payload)
+}
+
+func (s WebhookStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []models.DueDelivery, err error) {
+	ctx, span := startSpan(ctx, "ClaimWebhookDeliveries")
+	defer endSpan(span, &err)
+	return s.Next.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (s WebhookStore) RecordWebhookAttempt(ctx context.Context, id int64, attempt models.WebhookAttempt, status string, nextAttemptAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "RecordWebhookAttempt")
+	defer endSpan(span, &err)
+	return s.Next.RecordWebhookAttempt(ctx, id, attempt, status, nextAttemptAt)
+}
+
+func (s WebhookStore) GetWebhookDeliveries(ctx context.Context, webhookID, limit, offset int) (_ []models.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "GetWebhookDeliveries")
+	defer endSpan(span, &err)
+	return s.Next.GetWebhookDeliveries(ctx, webhookID, limit, offset)
+}
+
+func (s WebhookStore) GetWebhookDelivery(ctx context.Context, id int64) (_ models.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "GetWebhookDelivery")
+	defer endSpan(span, &err)
+	return s.Next.GetWebhookDelivery(ctx, id)
+}
+
+func (s WebhookStore) RedeliverWebhook(ctx context.Context, id int64) (_ models.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "RedeliverWebhook")
+	defer endSpan(span, &err)
+	return s.Next.RedeliverWebhook(ctx, id)
+}
+
+type OutboxStore struct {
+	Next store.OutboxStore
+}
+
+func (s OutboxStore) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) (_ []models.OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "ClaimOutboxEvents")
+	defer endSpan(span, &err)
+	return s.Next.ClaimOutboxEvents(ctx, limit, lease)
+}
+
+func (s OutboxStore) MarkOutboxProcessed(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "MarkOutboxProcessed")
+	defer endSpan(span, &err)
+	return s.Next.MarkOutboxProcessed(ctx, id)
+}
+
+func (s OutboxStore) RetryOutboxEvent(ctx context.Context, id int64, lastError string, retryAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "RetryOutboxEvent")
+	defer endSpan(span, &err)
+	return s.Next.RetryOutboxEvent(ctx, id, lastError, retryAt)
+}
+
+func (s OutboxStore) PruneOutbox(ctx context.Context, olderThan time.Duration) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "PruneOutbox")
+	defer endSpan(span, &err)
+	return s.Next.PruneOutbox(ctx, olderThan)
+}
+
+type AuditStore struct {
+	Next store.AuditStore
+}
+
+func (s AuditStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (err error) {
+	ctx, span := startSpan(ctx, "RecordAudit")
+	defer endSpan(span, &err)
+	return s.Next.RecordAudit(ctx, entry)
+}
+
+func (s AuditStore) GetAuditEntries(ctx context.Context, filter models.AuditFilter) (_ []models.AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "GetAuditEntries")
+	defer endSpan(span, &err)
+	return s.Next.GetAuditEntries(ctx, filter)
+}
+
+type UndoStore struct {
+	Next store.UndoStore
+}
+
+func (s UndoStore) RecordTodoOperation(ctx context.Context, op models.TodoOperation) (_ models.TodoOperation, err error) {
+	ctx, span := startSpan(ctx, "RecordTodoOperation")
+	defer endSpan(span, &err)
+	return s.Next.RecordTodoOperation(ctx, op)
+}
+
+func (s UndoStore) GetTodoOperation(ctx context.Context, token string) (_ models.TodoOperation, err error) {
+	ctx, span := startSpan(ctx, "GetTodoOperation")
+	defer endSpan(span, &err)
+	return s.Next.GetTodoOperation(ctx, token)
+}
+
+func (s UndoStore) MarkTodoOperationUndone(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "MarkTodoOperationUndone")
+	defer endSpan(span, &err)
+	return s.Next.MarkTodoOperationUndone(ctx, id)
+}
+
+func (s UndoStore) TakeExpiredTodoOperations(ctx context.Context, limit int) (_ []models.TodoOperation, err error) {
+	ctx, span := startSpan(ctx, "TakeExpiredTodoOperations")
+	defer endSpan(span, &err)
+	return s.Next.TakeExpiredTodoOperations(ctx, limit)
+}
+
+func (s UndoStore) DeleteUserTodoOperations(ctx context.Context, userID int) (_ []models.TodoOperation, err error) {
+	ctx, span := startSpan(ctx, "DeleteUserTodoOperations")
+	defer endSpan(span, &err)
+	return s.Next.DeleteUserTodoOperations(ctx, userID)
+}
+
+type FilterStore struct {
+	Next store.FilterStore
+}
+
+func (s FilterStore) CreateFilter(ctx context.Context, filter models.SavedFilter) (_ models.SavedFilter, err error) {
+	ctx, span := startSpan(ctx, "CreateFilter")
+	defer endSpan(span, &err)
+	return s.Next.CreateFilter(ctx, filter)
+}
+
+func (s FilterStore) GetFilters(ctx context.Context, userID int) (_ []models.SavedFilter, err error) {
+	ctx, span := startSpan(ctx, "GetFilters")
+	defer endSpan(span, &err)
+	return s.Next.GetFilters(ctx, userID)
+}
+
+func (s FilterStore) GetFilterByID(ctx context.Context, id int) (_ models.SavedFilter, err error) {
+	ctx, span := startSpan(ctx, "GetFilterByID")
+	defer endSpan(span, &err)
+	return s.Next.GetFilterByID(ctx, id)
+}
+
+func (s FilterStore) UpdateFilter(ctx context.Context, id int, filter models.SavedFilter) (_ models.SavedFilter, err error) {
+	ctx, span := startSpan(ctx, "UpdateFilter")
+	defer endSpan(span, &err)
+	return s.Next.UpdateFilter(ctx, id, filter)
+}
+
+func (s FilterStore) DeleteFilter(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteFilter")
+	defer endSpan(span, &err)
+	return s.Next.DeleteFilter(ctx, id)
+}
+
+// Transactor открывает спан на всю транзакцию и оборачивает методы внутри неё
+type Transactor struct {
+	Next store.Transactor
+}
+
+func (t Transactor) WithTx(ctx context.Context, fn func(store.Tx) error) (err error) {
+	ctx, span := startSpan(ctx, "WithTx")
+	defer endSpan(span, &err)
+	return t.Next.WithTx(ctx, func(tx store.Tx) error {
+		return fn(txStore{TodoStore{tx}, UserStore{tx}, UploadStore{tx}, WebhookStore{tx}, AuditStore{tx}, UndoStore{tx}})
+	})
+}
+
+type txStore struct {
+	TodoStore
+	UserStore
+	UploadStore
+	WebhookStore
+	AuditStore
+	UndoStore
+}
@@ -0,0 +1,176 @@
+package uploads
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"todo-api/store"
+)
+
+// Метрики сборщика, доступны на /debug/vars
+var gcMetrics = expvar.NewMap("upload_gc")
+
+// GCStats — результат одного прохода сборщика
+type GCStats struct {
+	DryRun     bool  `json:"dry_run"`
+	Scanned    int   `json:"scanned"`
+	Referenced int   `json:"referenced"`
+	TooYoung   int   `json:"too_young"`
+	Deleted    int   `json:"deleted"`
+	Failed     int   `json:"failed"`
+	BytesFreed int64 `json:"bytes_freed"`
+}
+
+// Collector удаляет из хранилища файлы, на которые не ссылается ни одна задача.
+// Файлы моложе GracePeriod не трогаются: запись в БД может ещё не закоммититься.
+// Это единственное место, где файлы удаляются: обработчики только снимают ссылки.
+type Collector struct {
+	Storage     Storage
+	Refs        store.UploadStore
+	Tx          store.Transactor
+	GracePeriod time.Duration
+	DryRun      bool
+
+	now func() time.Time
+}
+
+func NewCollector(storage Storage, refs store.UploadStore, tx store.Transactor, gracePeriod time.Duration, dryRun bool) *Collector {
+	return &Collector{
+		Storage:     storage,
+		Refs:        refs,
+		Tx:          tx,
+		GracePeriod: gracePeriod,
+		DryRun:      dryRun,
+		now:         time.Now,
+	}
+}
+
+// RunOnce выполняет один проход: список файлов -> сверка с БД -> удаление
+func (c *Collector) RunOnce(ctx context.Context) (GCStats, error) {
+	stats := GCStats{DryRun: c.DryRun}
+	gcMetrics.Add("runs", 1)
+
+	// Сначала снимаем список файлов, потом ссылки: файл, загруженный между
+	// этими шагами, либо попадёт в ссылки, либо будет моложе GracePeriod.
+	objects, err := c.Storage.List()
+	if err != nil {
+		gcMetrics.Add("errors", 1)
+		return stats, err
+	}
+
+	urls, err := c.Refs.GetPhotoURLs(ctx)
+	if err != nil {
+		gcMetrics.Add("errors", 1)
+		return stats, err
+	}
+	keys, err := c.Refs.GetBlobKeys(ctx)
+	if err != nil {
+		gcMetrics.Add("errors", 1)
+		return stats, err
+	}
+
+	referenced := make(map[string]struct{}, len(urls)+len(keys))
+	for _, u := range urls {
+		if key, ok := c.Storage.KeyFromURL(u); ok {
+			referenced[key] = struct{}{}
+		}
+	}
+	for _, key := range keys {
+		referenced[key] = struct{}{}
+	}
+
+	cutoff := c.now().Add(-c.GracePeriod)
+	for _, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		stats.Scanned++
+
+		if _, ok := referenced[obj.Key]; ok {
+			stats.Referenced++
+			continue
+		}
+		if obj.ModTime.After(cutoff) {
+			stats.TooYoung++
+			continue
+		}
+
+		if c.DryRun {
+			slog.Info("upload gc: would delete orphaned upload", "key", obj.Key, "bytes", obj.Size, "dry_run", true)
+			stats.Deleted++
+			stats.BytesFreed += obj.Size
+			continue
+		}
+
+		deleted, err := c.delete(ctx, obj.Key)
+		if err != nil {
+			slog.Error("upload gc: failed to delete orphaned upload", "key", obj.Key, "error", err)
+			stats.Failed++
+			continue
+		}
+		if !deleted {
+			// На файл сослались после снятия списка ссылок
+			stats.Referenced++
+			continue
+		}
+		slog.Info("upload gc: deleted orphaned upload", "key", obj.Key, "bytes", obj.Size)
+		stats.Deleted++
+		stats.BytesFreed += obj.Size
+	}
+
+	gcMetrics.Add("scanned", int64(stats.Scanned))
+	gcMetrics.Add("failed", int64(stats.Failed))
+	if !c.DryRun {
+		gcMetrics.Add("deleted", int64(stats.Deleted))
+		gcMetrics.Add("bytes_freed", stats.BytesFreed)
+	}
+	lastRun := new(expvar.Int)
+	lastRun.Set(c.now().Unix())
+	gcMetrics.Set("last_run_unix", lastRun)
+
+	return stats, nil
+}
+
+// delete удаляет файл, если на него по-прежнему нет ссылок. Строка blobs
+// заблокирована до коммита, поэтому AcquireBlob на тот же ключ дождётся
+// удаления файла и увидит, что его нет. Если коммит не удался, файл уже
+// удалён, а запись осталась с нулём ссылок — следующий проход повторит.
+func (c *Collector) delete(ctx context.Context, key string) (bool, error) {
+	deleted := false
+	err := c.Tx.WithTx(ctx, func(tx store.Tx) error {
+		unreferenced, err := tx.ClaimUnreferencedBlob(ctx, key)
+		if err != nil || !unreferenced {
+			return err
+		}
+		if err := c.Storage.Delete(key); err != nil {
+			return err
+		}
+		deleted = true
+		return nil
+	})
+	return deleted, err
+}
+
+// Run запускает сборщик по таймеру до отмены ctx
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := c.RunOnce(ctx)
+			if err != nil {
+				slog.Error("upload gc failed", "error", err)
+				continue
+			}
+			slog.Info("upload gc finished",
+				"scanned", stats.Scanned, "deleted", stats.Deleted, "bytes_freed", stats.BytesFreed,
+				"failed", stats.Failed, "dry_run", stats.DryRun)
+		}
+	}
+}
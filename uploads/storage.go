@@ -0,0 +1,170 @@
+package uploads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid upload key")
+
+// Object — файл, лежащий в хранилище
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage — хранилище загруженных файлов
+type Storage interface {
+	Save(r io.Reader) (key string, size int64, err error)
+	Delete(key string) error
+	// Exists проверяет, что файл с таким ключом лежит в хранилище
+	Exists(key string) (bool, error)
+	List() ([]Object, error)
+	URL(key string) string
+	KeyFromURL(rawURL string) (string, bool)
+	// CheckWritable проверяет, что в хранилище можно записывать файлы
+	CheckWritable() error
+}
+
+// LocalStorage хранит файлы в локальной папке и раздаёт их по BaseURL
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &LocalStorage{Dir: dir, BaseURL: baseURL}
+}
+
+// Save сохраняет содержимое r под ключом, равным SHA-256 содержимого.
+// Одинаковые файлы попадают в один и тот же ключ и хранятся один раз.
+func (s *LocalStorage) Save(r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", 0, err
+	}
+
+	tmpFile, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmpFile, hash), r)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	target := filepath.Join(s.Dir, key)
+	if _, err := os.Stat(target); err == nil {
+		// Такой файл уже есть — обновляем mtime, чтобы GC не удалил его
+		// до того, как новая ссылка попадёт в БД
+		now := time.Now()
+		return key, n, os.Chtimes(target, now, now)
+	}
+
+	if err := os.Rename(tmpFile.Name(), target); err != nil {
+		return "", 0, err
+	}
+	return key, n, nil
+}
+
+// Delete удаляет файл; отсутствие файла ошибкой не считается
+func (s *LocalStorage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.Dir, key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Exists проверяет наличие файла
+func (s *LocalStorage) Exists(key string) (bool, error) {
+	if !validKey(key) {
+		return false, ErrInvalidKey
+	}
+	_, err := os.Stat(filepath.Join(s.Dir, key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List возвращает все файлы верхнего уровня в папке хранилища
+func (s *LocalStorage) List() ([]Object, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// файл могли удалить между ReadDir и Info
+			continue
+		}
+		objects = append(objects, Object{Key: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return objects, nil
+}
+
+// CheckWritable создаёт и сразу удаляет временный файл в папке хранилища
+func (s *LocalStorage) CheckWritable() error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// URL возвращает публичную ссылку на файл
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + key
+}
+
+// KeyFromURL извлекает ключ файла из ссылки вида http://host/uploads/<key>
+func (s *LocalStorage) KeyFromURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	key := path.Base(u.Path)
+	if !validKey(key) {
+		return "", false
+	}
+	return key, true
+}
+
+func validKey(key string) bool {
+	return key != "" && key != "." && key != "/" && !strings.ContainsAny(key, `/\`) && key != ".."
+}
@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"net/url"
+	"regexp"
+	"unicode"
+
+	"todo-api/models"
+)
+
+const (
+	TitleMaxLength    = 200
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+	PasswordMinLength = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	PasswordMaxBytes = 72
+	URLMaxLength     = 2048
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Todo проверяет поля задачи, которые задаёт клиент
+func Todo(todo models.Todo) error {
+	v := New()
+	v.Required("title", todo.Title)
+	v.Length("title", todo.Title, 1, TitleMaxLength)
+	v.Printable("title", todo.Title)
+	return v.Err()
+}
+
+// Username проверяет имя пользователя: длина и допустимые символы
+func Username(v *Validator, field, username string) {
+	v.Required(field, username)
+	v.Length(field, username, UsernameMinLength, UsernameMaxLength)
+	v.Matches(field, username, usernamePattern, field+" may contain only latin letters, digits, '.', '_' and '-'")
+}
+
+// Password проверяет надёжность пароля: длина и наличие букв и цифр
+func Password(v *Validator, field, password string) {
+	v.Required(field, password)
+	v.Length(field, password, PasswordMinLength, PasswordMaxBytes)
+	v.Check(len(password) <= PasswordMaxBytes, field, CodeTooLong, field+" must be at most 72 bytes")
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	v.Check(hasLetter && hasDigit, field, CodeWeakPassword, field+" must contain both letters and digits")
+}
+
+// URL проверяет абсолютный http(s)-адрес
+func URL(v *Validator, field, raw string) {
+	v.Required(field, raw)
+	v.Length(field, raw, 1, URLMaxLength)
+
+	u, err := url.Parse(raw)
+	ok := err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+	v.Check(ok, field, CodeInvalidURL, field+" must be an absolute http or https URL without credentials")
+}
@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// Коды ошибок стабильны: клиенты могут на них опираться
+const (
+	CodeRequired     = "required"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeInvalidChars = "invalid_chars"
+	CodeWeakPassword = "weak_password"
+	CodeInvalidURL   = "invalid_url"
+	CodeInvalidValue = "invalid_value"
+)
+
+// Validator накапливает ошибки по полям; на каждое поле — не больше одной ошибки
+type Validator struct {
+	errors []models.FieldError
+	failed map[string]bool
+}
+
+func New() *Validator {
+	return &Validator{failed: map[string]bool{}}
+}
+
+// Add добавляет ошибку, если по этому полю ещё нет ошибок
+func (v *Validator) Add(field, code, message string) {
+	if v.failed[field] {
+		return
+	}
+	v.failed[field] = true
+	v.errors = append(v.errors, models.FieldError{Field: field, Code: code, Message: message})
+}
+
+// Check добавляет ошибку, если ok == false
+func (v *Validator) Check(ok bool, field, code, message string) {
+	if !ok {
+		v.Add(field, code, message)
+	}
+}
+
+// Required проверяет, что строка не пустая (без учёта пробелов)
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, CodeRequired, field+" is required")
+}
+
+// Length проверяет длину строки в символах
+func (v *Validator) Length(field, value string, min, max int) {
+	n := utf8.RuneCountInString(value)
+	v.Check(n >= min, field, CodeTooShort, fmt.Sprintf("%s must be at least %d characters", field, min))
+	v.Check(n <= max, field, CodeTooLong, fmt.Sprintf("%s must be at most %d characters", field, max))
+}
+
+// Matches проверяет строку регулярным выражением
+func (v *Validator) Matches(field, value string, re *regexp.Regexp, message string) {
+	v.Check(re.MatchString(value), field, CodeInvalidChars, message)
+}
+
+// Printable проверяет, что в строке нет управляющих символов
+func (v *Validator) Printable(field, value string) {
+	ok := strings.IndexFunc(value, unicode.IsControl) < 0
+	v.Check(ok, field, CodeInvalidChars, field+" must not contain control characters")
+}
+
+// Valid — ошибок не найдено
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Err возвращает *store.ValidationError со всеми ошибками или nil
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return &store.ValidationError{Fields: v.errors}
+}
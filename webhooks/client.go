@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var errForbiddenAddress = errors.New("webhook address is not public")
+
+// NewHTTPClient создаёт клиент для доставок. Адреса вебхуков задают
+// пользователи, поэтому без allowPrivate соединения с loopback, частными,
+// CGNAT и link-local адресами запрещены (защита от SSRF). Проверяется адрес,
+// к которому реально идёт соединение, — уже после DNS.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !isPublic(ip) {
+				return fmt.Errorf("%w: %s", errForbiddenAddress, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// Редиректы не выполняем: иначе адрес можно подменить после проверки
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Диапазоны, которые не покрывают методы netip.Addr
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // «эта сеть»; 0.x.x.x на Linux уходит на localhost
+	netip.MustParsePrefix("100.64.0.0/10"),  // CGNAT, адреса внутри сети провайдера
+	netip.MustParsePrefix("192.0.0.0/24"),   // служебные адреса IETF
+	netip.MustParsePrefix("198.18.0.0/15"),  // стенды для нагрузочных тестов
+	netip.MustParsePrefix("240.0.0.0/4"),    // зарезервированные, вместе с 255.255.255.255
+	netip.MustParsePrefix("64:ff9b:1::/48"), // NAT64 для локальных сетей
+}
+
+// Адреса NAT64 (64:ff9b::/96) и 6to4 (2002::/16) ведут на вложенный в них IPv4
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// embeddedIPv4 достаёт IPv4 из адреса NAT64 (последние 4 байта) или 6to4 (байты 2–5)
+func embeddedIPv4(ip netip.Addr) (netip.Addr, bool) {
+	b := ip.As16()
+	switch {
+	case nat64Prefix.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[12:16])), true
+	case sixToFour.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[2:6])), true
+	}
+	return netip.Addr{}, false
+}
+
+// isPublic сообщает, можно ли слать вебхук на ip. IPv4, записанный как
+// IPv6 (::ffff:127.0.0.1) или через NAT64 и 6to4, проверяется как IPv4.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if v4, ok := embeddedIPv4(ip); ok {
+		return isPublic(v4)
+	}
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
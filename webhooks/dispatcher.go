@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"todo-api/backoff"
+	"todo-api/metrics"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// maxErrorBody — сколько байт ответа получателя сохранять в журнал
+const maxErrorBody = 512
+
+// Dispatcher разбирает очередь доставок. Неудачная доставка повторяется
+// с экспоненциальной задержкой BackoffBase·2^(n-1), но не больше BackoffMax;
+// после MaxAttempts попыток доставка переходит в dead и ждёт ручного
+// повтора через API.
+type Dispatcher struct {
+	Store  store.WebhookStore
+	Client *http.Client
+
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// BatchSize — сколько доставок брать за раз; отправляются параллельно
+	BatchSize int
+	// Lease — на сколько доставка откладывается, пока её отправляет этот инстанс
+	Lease time.Duration
+}
+
+// Backoff возвращает задержку перед попыткой attempt+1
+func (d *Dispatcher) Backoff(attempt int) time.Duration {
+	return backoff.Exponential(attempt, d.BackoffBase, d.BackoffMax)
+}
+
+// RunOnce отправляет одну пачку наступивших доставок и возвращает их число
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	due, err := d.Store.ClaimWebhookDeliveries(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// Run разбирает очередь каждые interval до отмены ctx. Пока очередь
+// не пуста, следующая пачка берётся сразу.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("webhook dispatcher started", "interval", interval, "batch_size", d.BatchSize)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("webhook dispatch failed", "error", err)
+		}
+		if n == d.BatchSize && err == nil {
+			timer.Reset(0)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.DueDelivery) {
+	attempt := models.WebhookAttempt{Attempt: delivery.Attempts + 1}
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	attempt.DurationMS = int(time.Since(start).Milliseconds())
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	status := models.DeliverySucceeded
+	next := time.Now()
+	if err != nil {
+		if ctx.Err() != nil {
+			// Запрос оборвала остановка, а не получатель — попытку не считаем.
+			// Доставка отложена на Lease и после него снова попадёт в очередь.
+			return
+		}
+		msg := err.Error()
+		attempt.Error = &msg
+
+		status = models.DeliveryPending
+		next = next.Add(d.Backoff(attempt.Attempt))
+		if attempt.Attempt >= d.MaxAttempts {
+			status = models.DeliveryDead
+		}
+	}
+
+	result := status
+	if status == models.DeliveryPending {
+		result = "failed"
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	logger := slog.With("delivery_id", delivery.ID, "event", delivery.Event, "attempt", attempt.Attempt)
+	switch status {
+	case models.DeliveryDead:
+		logger.Warn("webhook delivery dead", "error", err)
+	case models.DeliveryPending:
+		logger.Info("webhook delivery failed, will retry", "error", err, "next_attempt_at", next)
+	}
+
+	// Получатель уже ответил: если не записать это из-за остановки, после
+	// аренды он получит ту же доставку ещё раз
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := d.Store.RecordWebhookAttempt(recordCtx, delivery.ID, attempt, status, next); err != nil {
+		logger.Error("failed to record webhook attempt", "error", err)
+	}
+}
+
+// send выполняет запрос; успех — ответ 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery models.DueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
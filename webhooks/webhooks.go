@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"todo-api/events"
+	"todo-api/models"
+	"todo-api/store"
+)
+
+// Events — события, на которые можно подписать вебхук
+var Events = []string{
+	events.TodoCreated,
+	events.TodoUpdated,
+	events.TodoCompleted,
+	events.TodoDeleted,
+	events.TodoRestored,
+	events.UserDeleted,
+}
+
+// Supported сообщает, можно ли подписаться на событие
+func Supported(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// payload — тело запроса доставки
+type payload struct {
+	// EventID совпадает у повторов одного события — по нему получатель отбрасывает дубликаты
+	EventID    int64        `json:"event_id,omitempty"`
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Todo       *models.Todo `json:"todo,omitempty"`
+	User       *models.User `json:"user,omitempty"`
+}
+
+// Enqueue ставит событие в очередь доставки всем подписанным вебхукам пользователя
+func Enqueue(ctx context.Context, s store.WebhookStore, ev events.Event) error {
+	if !Supported(ev.Type) {
+		return nil
+	}
+	body, err := json.Marshal(payload{EventID: ev.Seq, Event: ev.Type, OccurredAt: ev.At, Todo: ev.Todo, User: ev.User})
+	if err != nil {
+		return err
+	}
+	_, err = s.EnqueueWebhookDeliveries(ctx, ev.UserID, ev.Type, body)
+	return err
+}
+
+// Enqueuer — events.Publisher, превращающий события в доставки вебхуков
+type Enqueuer struct {
+	Store store.WebhookStore
+}
+
+func (e Enqueuer) Publish(ctx context.Context, ev events.Event) error {
+	// Вебхуки удалённого пользователя удаляются вместе с ним, поэтому
+	// user.deleted ставится в очередь ещё в транзакции удаления
+	if ev.Type == events.UserDeleted {
+		return nil
+	}
+	return Enqueue(ctx, e.Store, ev)
+}
+
+// NewSecret генерирует секрет для подписи доставок
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign возвращает подпись "sha256=<hex>" от "<timestamp>.<body>". Метка
+// времени входит в подпись, чтобы получатель мог отбрасывать повторы
+// старых запросов.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"todo.created"}`)
+	at := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp time.Time
+		body      []byte
+		want      string
+	}{
+		{
+			// Эталон посчитан независимо: HMAC-SHA256("whsec_test", "1700000000." + body)
+			name:      "known vector",
+			secret:    "whsec_test",
+			timestamp: at,
+			body:      body,
+			want:      "sha256=0db4dcd7e7c5bc797d5b40bad35628d1b595ae10063a537e47ef00ffc5b800c9",
+		},
+		{
+			name:      "sub-second part ignored",
+			secret:    "whsec_test",
+			timestamp: at.Add(999 * time.Millisecond),
+			body:      body,
+			want:      "sha256=0db4dcd7e7c5bc797d5b40bad35628d1b595ae10063a537e47ef00ffc5b800c9",
+		},
+		{
+			name:      "empty secret and body",
+			timestamp: time.Unix(0, 0),
+			want:      "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, tt.body); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDependsOnEveryInput(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	base := Sign("whsec_test", at, []byte("{}"))
+
+	variants := map[string]string{
+		"secret":    Sign("whsec_other", at, []byte("{}")),
+		"timestamp": Sign("whsec_test", at.Add(time.Second), []byte("{}")),
+		"body":      Sign("whsec_test", at, []byte("{ }")),
+	}
+	for name, sig := range variants {
+		if sig == base {
+			t.Errorf("changing %s did not change the signature", name)
+		}
+	}
+}